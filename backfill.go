@@ -0,0 +1,248 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/celerfi/stellar-indexer-go/config"
+	tx_handlers "github.com/celerfi/stellar-indexer-go/handlers"
+	"github.com/celerfi/stellar-indexer-go/ledgersource"
+	"github.com/celerfi/stellar-indexer-go/logging"
+	"github.com/celerfi/stellar-indexer-go/pipeline"
+	"github.com/celerfi/stellar-indexer-go/sink"
+	"github.com/stellar/go/ingest/ledgerbackend"
+	"golang.org/x/sync/errgroup"
+)
+
+type ledgerChunk struct {
+	from uint32
+	to   uint32
+}
+
+// backfillCursorPrefix starts the name of every backfill chunk cursor.
+const backfillCursorPrefix = "backfill:"
+
+// cursorName is the sink cursor tracking the chunk. A chunk is complete
+// once its cursor reaches the end of the range.
+func (c ledgerChunk) cursorName() string {
+	return backfillCursorPrefix + c.key()
+}
+
+func (c ledgerChunk) key() string {
+	return fmt.Sprintf("%d-%d", c.from, c.to)
+}
+
+// runBackfill implements `backfill --from N --to M --workers K`. The range is
+// split into fixed size chunks which are ingested concurrently, each from its
+// own bounded ledger source. Progress is committed per ledger, so an
+// interrupted backfill picks every chunk up where it stopped when rerun with
+// the same arguments. On shutdown each chunk finishes its ledger in flight.
+func runBackfill(ctx context.Context, cfg config.Config, out sink.Sink, args []string) error {
+	flags := flag.NewFlagSet("backfill", flag.ExitOnError)
+	from := flags.Uint("from", 0, "first ledger to ingest")
+	to := flags.Uint("to", 0, "last ledger to ingest (inclusive)")
+	workers := flags.Int("workers", 4, "chunks processed concurrently")
+	chunkSize := flags.Uint("chunk-size", 10000, "ledgers per chunk")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *from == 0 || *to == 0 || *from > *to {
+		return errors.New("--from and --to must be set and --from must not be after --to")
+	}
+	if *workers < 1 || *chunkSize < 1 {
+		return errors.New("--workers and --chunk-size must be positive")
+	}
+
+	chunks := splitRange(uint32(*from), uint32(*to), uint32(*chunkSize))
+	if err := checkChunkCursors(ctx, out, uint32(*from), uint32(*to), chunks); err != nil {
+		return err
+	}
+	slog.Info("Backfilling ledgers", "from", *from, "to", *to, "chunks", len(chunks), "workers", *workers)
+
+	initHandlers(ctx, cfg, out)
+	registry, err := newRegistry(cfg.Processors)
+	if err != nil {
+		return err
+	}
+	pool := newHandlerPool(cfg.Workers)
+	defer pool.Close()
+
+	queue := make(chan ledgerChunk)
+	group, groupCtx := errgroup.WithContext(ctx)
+	drainCtx := drainContext(groupCtx, cfg.ShutdownTimeout)
+	for i := 0; i < *workers; i++ {
+		group.Go(func() error {
+			for chunk := range queue {
+				if err := backfillChunk(groupCtx, drainCtx, cfg, out, pool, registry, chunk); err != nil {
+					return fmt.Errorf("chunk %d-%d: %w", chunk.from, chunk.to, err)
+				}
+			}
+			return nil
+		})
+	}
+
+	group.Go(func() error {
+		defer close(queue)
+		for _, chunk := range chunks {
+			select {
+			case queue <- chunk:
+			case <-groupCtx.Done():
+				return nil
+			}
+		}
+		return nil
+	})
+
+	if err := group.Wait(); err != nil {
+		if ctx.Err() != nil {
+			slog.Info("Backfill interrupted, rerun with the same arguments to resume", logging.Err(err))
+			return nil
+		}
+		return err
+	}
+	slog.Info("Backfill complete")
+	return nil
+}
+
+// backfillChunk fetches ledgers on ctx and processes and commits them on
+// drainCtx, see drainContext.
+func backfillChunk(ctx, drainCtx context.Context, cfg config.Config, out sink.Sink, pool *pipeline.Pool, registry *tx_handlers.Registry, chunk ledgerChunk) error {
+	last, err := out.Cursor(ctx, chunk.cursorName())
+	if err != nil {
+		return err
+	}
+	if last >= chunk.to {
+		slog.Info("Chunk already complete", "from", chunk.from, "to", chunk.to)
+		return nil
+	}
+	start := chunk.from
+	if last >= start {
+		start = last + 1
+	}
+
+	backend, err := ledgersource.New(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create ledger source: %w", err)
+	}
+	defer backend.Close()
+	if err := backend.PrepareRange(ctx, ledgerbackend.BoundedRange(start, chunk.to)); err != nil {
+		return fmt.Errorf("failed to prepare range: %w", err)
+	}
+
+	for seq := start; seq <= chunk.to; seq++ {
+		ledger, ok, err := fetchLedger(ctx, drainCtx, out, backend, seq, cfg.Source.Retry, chunk.cursorName())
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get ledger %d: %w", seq, err)
+		}
+		if !ok {
+			continue
+		}
+		if err := indexLedger(drainCtx, out, pool, registry, cfg.Network.Passphrase, chunk.cursorName(), ledger); err != nil {
+			return fmt.Errorf("failed to commit ledger %d: %w", seq, err)
+		}
+	}
+
+	slog.Info("Chunk complete", "from", chunk.from, "to", chunk.to)
+	return nil
+}
+
+// checkChunkCursors refuses to start when an earlier backfill left an
+// unfinished chunk in [from, to] that is not one of chunks, as it would
+// after a rerun with another --chunk-size or --from: none of the new chunk
+// cursors would match and its ledgers would be ingested again. Finished
+// chunks do not matter, since ingesting their ledgers again is safe.
+func checkChunkCursors(ctx context.Context, out sink.Sink, from, to uint32, chunks []ledgerChunk) error {
+	cursors, err := out.Cursors(ctx, backfillCursorPrefix)
+	if err != nil {
+		return err
+	}
+	planned := map[string]bool{}
+	for _, chunk := range chunks {
+		planned[chunk.key()] = true
+	}
+
+	var unfinished []ledgerChunk
+	for key, last := range cursors {
+		var chunk ledgerChunk
+		if _, err := fmt.Sscanf(key, "%d-%d", &chunk.from, &chunk.to); err != nil {
+			continue
+		}
+		if planned[key] || last >= chunk.to || chunk.to < from || chunk.from > to {
+			continue
+		}
+		unfinished = append(unfinished, chunk)
+	}
+	if len(unfinished) == 0 {
+		return nil
+	}
+	slices.SortFunc(unfinished, func(a, b ledgerChunk) int { return cmp.Compare(a.from, b.from) })
+
+	var keys, resume []string
+	for _, chunk := range unfinished {
+		keys = append(keys, chunk.key())
+		resume = append(resume, resumeArgs(chunk.from, chunk.to, chunk.to-chunk.from+1))
+	}
+	if original, ok := originalRun(cursors, unfinished); ok {
+		resume = []string{original}
+	}
+	return fmt.Errorf("ledgers %d-%d overlap unfinished backfill chunks %s of an earlier run; resume it first with backfill %s",
+		from, to, strings.Join(keys, ", "), strings.Join(resume, ", then "))
+}
+
+// originalRun returns the arguments of the backfill that planned every chunk
+// in unfinished, when they all come from one run. Its --from reaches back
+// over the run's finished chunks found in cursors.
+func originalRun(cursors map[string]uint32, unfinished []ledgerChunk) (string, bool) {
+	from, to, size := unfinished[0].from, unfinished[0].to, uint32(0)
+	for _, chunk := range unfinished {
+		to = max(to, chunk.to)
+		size = max(size, chunk.to-chunk.from+1)
+	}
+	for from > size {
+		if _, ok := cursors[ledgerChunk{from: from - size, to: from - 1}.key()]; !ok {
+			break
+		}
+		from -= size
+	}
+	planned := map[ledgerChunk]bool{}
+	for _, chunk := range splitRange(from, to, size) {
+		planned[chunk] = true
+	}
+	for _, chunk := range unfinished {
+		if !planned[chunk] {
+			return "", false
+		}
+	}
+	return resumeArgs(from, to, size), true
+}
+
+func resumeArgs(from, to, size uint32) string {
+	return fmt.Sprintf("--from %d --to %d --chunk-size %d", from, to, size)
+}
+
+// splitRange cuts [from, to] into consecutive chunks of at most size ledgers.
+func splitRange(from, to, size uint32) []ledgerChunk {
+	var chunks []ledgerChunk
+	for start := from; start <= to; {
+		end := to
+		if to-start >= size {
+			end = start + size - 1
+		}
+		chunks = append(chunks, ledgerChunk{from: start, to: end})
+		if end == to {
+			break
+		}
+		start = end + 1
+	}
+	return chunks
+}
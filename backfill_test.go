@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/celerfi/stellar-indexer-go/models"
+	"github.com/celerfi/stellar-indexer-go/sink"
+)
+
+func TestCheckChunkCursors(t *testing.T) {
+	tests := []struct {
+		name      string
+		cursors   map[string]uint32
+		from, to  uint32
+		chunkSize uint32
+		wantErr   string
+	}{
+		{"no earlier run", nil, 100, 399, 100, ""},
+		{"same plan resumes", map[string]uint32{"100-199": 150, "200-299": 299}, 100, 399, 100, ""},
+		{"finished chunks do not block", map[string]uint32{"100-149": 149, "150-199": 199}, 120, 399, 100, ""},
+		{"outside the range", map[string]uint32{"500-599": 510}, 100, 399, 100, ""},
+		{
+			"other chunk size",
+			map[string]uint32{"100-149": 149, "150-199": 160, "200-249": 210, "250-260": 250},
+			100, 399, 100,
+			"resume it first with backfill --from 100 --to 260 --chunk-size 50",
+		},
+		{
+			"same chunk size, other start",
+			map[string]uint32{"100-199": 120},
+			150, 399, 100,
+			"resume it first with backfill --from 100 --to 199 --chunk-size 100",
+		},
+		{
+			"chunks of two runs",
+			map[string]uint32{"100-199": 120, "150-229": 160},
+			100, 399, 50,
+			"backfill --from 100 --to 199 --chunk-size 100, then --from 150 --to 229 --chunk-size 80",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			out := sink.NewMemory()
+			for key, last := range tt.cursors {
+				if err := out.WriteLedger(ctx, models.LedgerBatch{LedgerSequence: last}, backfillCursorPrefix+key); err != nil {
+					t.Fatal(err)
+				}
+			}
+			err := checkChunkCursors(ctx, out, tt.from, tt.to, splitRange(tt.from, tt.to, tt.chunkSize))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
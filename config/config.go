@@ -0,0 +1,300 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+const defaultConfigFile = "dev.env"
+
+// Config is everything the indexer is configured with. It is loaded once at
+// startup by Load and handed to the components that need it.
+type Config struct {
+	Environment string // testing or production
+	Network     NetworkProfile
+	DB          DBConfig
+	RPC         RPCConfig
+	Horizon     HorizonConfig
+	Source      SourceConfig
+	Sink        SinkConfig
+	Workers     WorkerConfig
+	Processors  []string // empty enables every processor
+	HTTP        HTTPConfig
+	Log         LogConfig
+
+	ShutdownTimeout time.Duration // how long the ledger in flight may take to finish after SIGTERM
+}
+
+type DBConfig struct {
+	Host           string
+	Port           int
+	User           string
+	Password       string
+	Name           string
+	SSLMode        string
+	MaxConns       int
+	MinConns       int
+	ConnectTimeout time.Duration
+}
+
+// DSN is the connection string for the database, without pool settings.
+func (d DBConfig) DSN() string {
+	query := url.Values{}
+	query.Set("sslmode", d.SSLMode)
+	query.Set("connect_timeout", strconv.Itoa(int(d.ConnectTimeout.Seconds())))
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(d.User, d.Password),
+		Host:     net.JoinHostPort(d.Host, strconv.Itoa(d.Port)),
+		Path:     "/" + d.Name,
+		RawQuery: query.Encode(),
+	}
+	return dsn.String()
+}
+
+type RPCConfig struct {
+	URL     string
+	Timeout time.Duration
+}
+
+// HorizonConfig holds the Horizon client settings; the URL comes from the
+// network profile.
+type HorizonConfig struct {
+	Timeout time.Duration
+}
+
+type SourceConfig struct {
+	Type      string // rpc, datastore or file
+	Dir       string // directory of <seq>.xdr / <seq>.xdr.zst files for the file source
+	Datastore DatastoreConfig
+	Retry     RetryConfig
+}
+
+// RetryConfig bounds the exponential backoff used when fetching a ledger
+// fails transiently. MaxAttempts only applies to backfill and reprocess:
+// live ingestion always retries at MaxDelay until the source is back.
+type RetryConfig struct {
+	MaxAttempts int // backfill and reprocess only, 0 retries forever
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+type DatastoreConfig struct {
+	Type              string // GCS or S3
+	BucketPath        string
+	Region            string
+	EndpointURL       string
+	LedgersPerFile    uint32
+	FilesPerPartition uint32
+}
+
+// SinkConfig selects where derived rows are written.
+type SinkConfig struct {
+	Type          string // postgres, jsonl or stdout
+	Dir           string // output directory of the jsonl sink
+	RotateLedgers uint32 // ledgers per jsonl file
+}
+
+type WorkerConfig struct {
+	Count     int // handler workers
+	QueueSize int // queued handler tasks before fetching blocks pauses
+}
+
+type HTTPConfig struct {
+	Addr        string // listen address of /metrics, /healthz and /readyz, "off" disables
+	ReadyMaxLag uint32 // ledgers ingestion may trail the source before /readyz fails
+}
+
+type LogConfig struct {
+	Level  string // debug, info, warn or error
+	Format string // text or json
+}
+
+// Default returns the configuration used for anything left unset.
+func Default() Config {
+	return Config{
+		Network: networkProfiles["pubnet"],
+		DB: DBConfig{
+			Port:           5432,
+			SSLMode:        "prefer",
+			MaxConns:       5,
+			ConnectTimeout: 10 * time.Second,
+		},
+		RPC:     RPCConfig{Timeout: 10 * time.Second},
+		Horizon: HorizonConfig{Timeout: 10 * time.Second},
+		Source: SourceConfig{
+			Type:  "rpc",
+			Retry: RetryConfig{MaxAttempts: 10, BaseDelay: time.Second, MaxDelay: time.Minute},
+		},
+		Sink:    SinkConfig{Type: "postgres", Dir: "output", RotateLedgers: 1000},
+		Workers: WorkerConfig{Count: 4, QueueSize: 64},
+		HTTP:    HTTPConfig{Addr: ":9090", ReadyMaxLag: 10},
+		Log:     LogConfig{Level: "info", Format: "text"},
+
+		ShutdownTimeout: 30 * time.Second,
+	}
+}
+
+// Load builds the configuration from, in increasing precedence, the
+// defaults, the config file, the environment and command line flags. The
+// config file uses the KEY=VALUE format of dev.env and the same keys as the
+// environment; --config selects it. The arguments left after the flags (the
+// subcommand and its flags) are returned.
+func Load(args []string) (Config, []string, error) {
+	flags := flag.NewFlagSet("indexer", flag.ContinueOnError)
+	configFile := flags.String("config", defaultConfigFile, "config file of KEY=VALUE lines")
+	flagValues := map[string]*string{}
+	for _, s := range settings {
+		flagValues[s.key] = flags.String(s.flagName(), "", s.usage+" ("+s.key+")")
+	}
+	if err := flags.Parse(args); err != nil {
+		return Config{}, nil, err
+	}
+
+	values := map[string]string{}
+	fileValues, err := godotenv.Read(*configFile)
+	if err != nil {
+		explicit := false
+		flags.Visit(func(f *flag.Flag) { explicit = explicit || f.Name == "config" })
+		if explicit || !errors.Is(err, os.ErrNotExist) {
+			return Config{}, nil, fmt.Errorf("failed to read config file %s: %w", *configFile, err)
+		}
+	}
+	for key, val := range fileValues {
+		values[key] = val
+	}
+	for _, s := range settings {
+		if val := os.Getenv(s.key); val != "" {
+			values[s.key] = val
+		}
+	}
+	flags.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if s.flagName() == f.Name {
+				values[s.key] = *flagValues[s.key]
+			}
+		}
+	})
+
+	cfg, err := build(values)
+	if err != nil {
+		return Config{}, nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, nil, err
+	}
+	return cfg, flags.Args(), nil
+}
+
+// build applies values on top of the defaults in settings order, so the
+// network profile is picked before its overrides are applied.
+func build(values map[string]string) (Config, error) {
+	cfg := Default()
+	var errs []error
+	for _, s := range settings {
+		val, ok := values[s.key]
+		if !ok || val == "" {
+			continue
+		}
+		if err := s.set(&cfg, val); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.key, err))
+		}
+	}
+	return cfg, errors.Join(errs...)
+}
+
+// Validate reports every missing or inconsistent setting at once.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Environment == "testing" || c.Environment == "production",
+		"DEPLOYMENT_ENVIRONMENT must be set: options (testing, production)")
+	check(c.Network.Passphrase != "", "NETWORK_PASSPHRASE must not be empty")
+	_, err := url.ParseRequestURI(c.Network.HorizonURL)
+	check(err == nil, "HORIZON_URL %q is not a valid URL", c.Network.HorizonURL)
+
+	switch c.Sink.Type {
+	case "postgres":
+		check(c.DB.Host != "", "DB_HOST must be set for the postgres sink")
+		check(c.DB.User != "", "DB_USER must be set for the postgres sink")
+		check(c.DB.Name != "", "DB_NAME must be set for the postgres sink")
+	case "jsonl":
+		check(c.Sink.Dir != "", "SINK_DIR must be set for the jsonl sink")
+		check(c.Sink.RotateLedgers > 0, "SINK_ROTATE_LEDGERS must be positive")
+	case "stdout":
+	default:
+		check(false, "unknown SINK %q: options (postgres, jsonl, stdout)", c.Sink.Type)
+	}
+	check(c.DB.Port > 0 && c.DB.Port < 65536, "DB_PORT %d is out of range", c.DB.Port)
+	check(oneOf(c.DB.SSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full"),
+		"unknown DB_SSLMODE %q: options (disable, allow, prefer, require, verify-ca, verify-full)", c.DB.SSLMode)
+	check(c.DB.MaxConns > 0, "DB_MAX_CONNS must be positive")
+	check(c.DB.MinConns >= 0 && c.DB.MinConns <= c.DB.MaxConns, "DB_MIN_CONNS must be between 0 and DB_MAX_CONNS")
+	check(c.DB.ConnectTimeout >= time.Second, "DB_CONNECT_TIMEOUT must be at least 1s")
+
+	check(c.RPC.Timeout > 0, "RPC_TIMEOUT must be positive")
+	check(c.Horizon.Timeout > 0, "HORIZON_TIMEOUT must be positive")
+
+	switch c.Source.Type {
+	case "rpc":
+		check(c.RPC.URL != "", "RPC_URL must be set for the rpc ledger source")
+	case "datastore":
+		check(c.Source.Datastore.Type != "", "DATASTORE_TYPE must be set for the datastore ledger source")
+		check(c.Source.Datastore.BucketPath != "", "DATASTORE_BUCKET_PATH must be set for the datastore ledger source")
+	case "file":
+		check(c.Source.Dir != "", "LEDGER_DIR must be set for the file ledger source")
+	default:
+		check(false, "unknown LEDGER_SOURCE %q: options (rpc, datastore, file)", c.Source.Type)
+	}
+
+	check(c.Source.Retry.MaxAttempts >= 0, "FETCH_MAX_ATTEMPTS must not be negative")
+	check(c.Source.Retry.BaseDelay > 0 && c.Source.Retry.BaseDelay <= c.Source.Retry.MaxDelay,
+		"FETCH_RETRY_BASE_DELAY must be positive and at most FETCH_RETRY_MAX_DELAY")
+
+	check(c.Workers.Count > 0, "WORKER_COUNT must be positive")
+	check(c.Workers.QueueSize > 0, "WORKER_QUEUE_SIZE must be positive")
+
+	check(c.ShutdownTimeout > 0, "SHUTDOWN_TIMEOUT must be positive")
+
+	check(oneOf(c.Log.Level, "debug", "info", "warn", "error"), "unknown LOG_LEVEL %q: options (debug, info, warn, error)", c.Log.Level)
+	check(oneOf(c.Log.Format, "text", "json"), "unknown LOG_FORMAT %q: options (text, json)", c.Log.Format)
+
+	return errors.Join(errs...)
+}
+
+// LogValue logs the configuration with secrets redacted.
+func (c Config) LogValue() slog.Value {
+	attrs := make([]slog.Attr, 0, len(settings))
+	for _, s := range settings {
+		val := s.get(&c)
+		if s.secret && val != "" {
+			val = "REDACTED"
+		}
+		attrs = append(attrs, slog.String(strings.ToLower(s.key), val))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+func oneOf(val string, options ...string) bool {
+	for _, option := range options {
+		if val == option {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/stellar/go/network"
+)
+
+// NetworkContracts is the set of protocol contracts the indexer tracks on a
+// network. Empty entries mean the protocol is not deployed (or not known) there.
+// The aquarius processor only indexes trades of Aquarius and AquariusRouter,
+// the reflector processor set_price calls of ReflectorOracles.
+type NetworkContracts struct {
+	Aquarius         string
+	AquariusRouter   string
+	Soroswap         string
+	SoroswapRouter   string
+	Lumenswap        string
+	ReflectorOracles []string
+}
+
+// NetworkProfile bundles everything that differs between Stellar networks.
+type NetworkProfile struct {
+	Name       string
+	Passphrase string
+	HorizonURL string
+	Contracts  NetworkContracts
+}
+
+var networkProfiles = map[string]NetworkProfile{
+	"pubnet": {
+		Name:       "pubnet",
+		Passphrase: network.PublicNetworkPassphrase,
+		HorizonURL: "https://horizon.stellar.org",
+		Contracts: NetworkContracts{
+			Aquarius:       "CAJXBOGWSRFT7Q7ZKHVTPWGODOBBSPYQVKN2WSMN2WFMPAXX2CETEBAZ",
+			AquariusRouter: "CBQDHNBFBZYE4MKPWBSJOPIYLW4SFSXAXUTSXJN76GNKYVYPCKWC6QUK",
+			Soroswap:       "CA4HEQTL2WPEUYKYKCDOHCDNIV4QHNJ7EL4J4NQ6VADP7SYHVRYZ7AW2",
+			SoroswapRouter: "CAG5LRYQ5JVEUI5TEID72EYOVX44TTUJT5BQR2J6J77FH65PCCFAJDDH",
+			Lumenswap:      "GAB7STHVD5BDH3EEYXPI3OM7PCS4V443PYB5FNT6CFGJVPDLMKDM24WK",
+			ReflectorOracles: []string{
+				"CAFJZQWSED6YAWZU3GWRTOCNPPCGBN32L7QV43XX5LZLFTK6JLN34DLN",
+				"CALI2BYU2JE6WVRUFYTS6MSBNEHGJ35P4AVCZYF3B6QOE3QKOB2PLE6M",
+				"CBKGPWGKSKZF52CFHMTRR23TBWTPMRDIYZ4O2P5VS65BMHYH4DXMCJZC",
+			},
+		},
+	},
+	"testnet": {
+		Name:       "testnet",
+		Passphrase: network.TestNetworkPassphrase,
+		HorizonURL: "https://horizon-testnet.stellar.org",
+	},
+	"futurenet": {
+		Name:       "futurenet",
+		Passphrase: network.FutureNetworkPassphrase,
+		HorizonURL: "https://horizon-futurenet.stellar.org",
+	},
+	"standalone": {
+		Name:       "standalone",
+		Passphrase: "Standalone Network ; February 2017",
+		HorizonURL: "http://localhost:8000",
+	},
+}
+
+// networkProfile returns the built-in profile of the named network.
+func networkProfile(name string) (NetworkProfile, error) {
+	profile, ok := networkProfiles[name]
+	if !ok {
+		return networkProfiles["pubnet"], fmt.Errorf("unknown network %q: options (pubnet, testnet, futurenet, standalone)", name)
+	}
+	return profile, nil
+}
+
+func splitList(val string) []string {
+	var items []string
+	for _, item := range strings.Split(val, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// setting is one configuration key. The key is used in the config file and
+// the environment; the flag name is derived from it (DB_HOST - --db-host).
+type setting struct {
+	key    string
+	usage  string
+	secret bool
+	set    func(c *Config, val string) error
+	get    func(c *Config) string
+}
+
+func (s setting) flagName() string {
+	return strings.ReplaceAll(strings.ToLower(s.key), "_", "-")
+}
+
+// settings lists every key in the order it is applied. STELLAR_NETWORK comes
+// before the per-network overrides because it replaces the whole profile.
+var settings = []setting{
+	stringSetting("DEPLOYMENT_ENVIRONMENT", "testing or production", func(c *Config) *string { return &c.Environment }),
+
+	{
+		key:   "STELLAR_NETWORK",
+		usage: "pubnet, testnet, futurenet or standalone",
+		set: func(c *Config, val string) error {
+			profile, err := networkProfile(val)
+			c.Network = profile
+			return err
+		},
+		get: func(c *Config) string { return c.Network.Name },
+	},
+	stringSetting("NETWORK_PASSPHRASE", "network passphrase override", func(c *Config) *string { return &c.Network.Passphrase }),
+	stringSetting("HORIZON_URL", "Horizon URL override", func(c *Config) *string { return &c.Network.HorizonURL }),
+	stringSetting("AQUARIUS_CONTRACT_ID", "Aquarius contract override", func(c *Config) *string { return &c.Network.Contracts.Aquarius }),
+	stringSetting("AQUARIUS_ROUTER_CONTRACT_ID", "Aquarius router contract override", func(c *Config) *string { return &c.Network.Contracts.AquariusRouter }),
+	stringSetting("SOROSWAP_CONTRACT_ID", "Soroswap contract override", func(c *Config) *string { return &c.Network.Contracts.Soroswap }),
+	stringSetting("SOROSWAP_ROUTER_CONTRACT_ID", "Soroswap router contract override", func(c *Config) *string { return &c.Network.Contracts.SoroswapRouter }),
+	stringSetting("LUMENSWAP_CONTRACT_ID", "Lumenswap contract override", func(c *Config) *string { return &c.Network.Contracts.Lumenswap }),
+	listSetting("REFLECTOR_CONTRACTS", "comma separated Reflector oracle contracts override", func(c *Config) *[]string { return &c.Network.Contracts.ReflectorOracles }),
+
+	stringSetting("DB_HOST", "database host", func(c *Config) *string { return &c.DB.Host }),
+	intSetting("DB_PORT", "database port", func(c *Config) *int { return &c.DB.Port }),
+	stringSetting("DB_USER", "database user", func(c *Config) *string { return &c.DB.User }),
+	secretSetting("DB_PASSWORD", "database password", func(c *Config) *string { return &c.DB.Password }),
+	stringSetting("DB_NAME", "database name", func(c *Config) *string { return &c.DB.Name }),
+	stringSetting("DB_SSLMODE", "disable, allow, prefer, require, verify-ca or verify-full", func(c *Config) *string { return &c.DB.SSLMode }),
+	intSetting("DB_MAX_CONNS", "maximum pooled database connections", func(c *Config) *int { return &c.DB.MaxConns }),
+	intSetting("DB_MIN_CONNS", "minimum pooled database connections", func(c *Config) *int { return &c.DB.MinConns }),
+	durationSetting("DB_CONNECT_TIMEOUT", "database connect timeout", func(c *Config) *time.Duration { return &c.DB.ConnectTimeout }),
+
+	stringSetting("RPC_URL", "Soroban RPC URL", func(c *Config) *string { return &c.RPC.URL }),
+	durationSetting("RPC_TIMEOUT", "timeout of contract calls to the RPC node", func(c *Config) *time.Duration { return &c.RPC.Timeout }),
+	durationSetting("HORIZON_TIMEOUT", "timeout of Horizon requests", func(c *Config) *time.Duration { return &c.Horizon.Timeout }),
+
+	stringSetting("LEDGER_SOURCE", "rpc, datastore or file", func(c *Config) *string { return &c.Source.Type }),
+	stringSetting("LEDGER_DIR", "directory of <seq>.xdr / <seq>.xdr.zst files for the file source", func(c *Config) *string { return &c.Source.Dir }),
+	stringSetting("DATASTORE_TYPE", "GCS or S3", func(c *Config) *string { return &c.Source.Datastore.Type }),
+	stringSetting("DATASTORE_BUCKET_PATH", "datastore bucket path", func(c *Config) *string { return &c.Source.Datastore.BucketPath }),
+	stringSetting("DATASTORE_REGION", "datastore region", func(c *Config) *string { return &c.Source.Datastore.Region }),
+	stringSetting("DATASTORE_ENDPOINT_URL", "datastore endpoint URL", func(c *Config) *string { return &c.Source.Datastore.EndpointURL }),
+	uint32Setting("DATASTORE_LEDGERS_PER_FILE", "ledgers per datastore file", func(c *Config) *uint32 { return &c.Source.Datastore.LedgersPerFile }),
+	uint32Setting("DATASTORE_FILES_PER_PARTITION", "files per datastore partition", func(c *Config) *uint32 { return &c.Source.Datastore.FilesPerPartition }),
+
+	intSetting("FETCH_MAX_ATTEMPTS", "attempts backfill and reprocess make to fetch a ledger before giving up, 0 retries forever (live ingestion always retries)", func(c *Config) *int { return &c.Source.Retry.MaxAttempts }),
+	durationSetting("FETCH_RETRY_BASE_DELAY", "first backoff delay after a failed ledger fetch", func(c *Config) *time.Duration { return &c.Source.Retry.BaseDelay }),
+	durationSetting("FETCH_RETRY_MAX_DELAY", "longest backoff delay between ledger fetches", func(c *Config) *time.Duration { return &c.Source.Retry.MaxDelay }),
+
+	stringSetting("SINK", "postgres, jsonl or stdout", func(c *Config) *string { return &c.Sink.Type }),
+	stringSetting("SINK_DIR", "output directory of the jsonl sink", func(c *Config) *string { return &c.Sink.Dir }),
+	uint32Setting("SINK_ROTATE_LEDGERS", "ledgers per jsonl sink file", func(c *Config) *uint32 { return &c.Sink.RotateLedgers }),
+
+	intSetting("WORKER_COUNT", "handler workers", func(c *Config) *int { return &c.Workers.Count }),
+	intSetting("WORKER_QUEUE_SIZE", "queued handler tasks before fetching blocks pauses", func(c *Config) *int { return &c.Workers.QueueSize }),
+	listSetting("ENABLED_PROCESSORS", "comma separated processors to run, empty enables all", func(c *Config) *[]string { return &c.Processors }),
+
+	stringSetting("METRICS_ADDR", `listen address of /metrics, /healthz and /readyz, "off" disables`, func(c *Config) *string { return &c.HTTP.Addr }),
+	uint32Setting("READY_MAX_LAG", "ledgers ingestion may trail the source before /readyz fails", func(c *Config) *uint32 { return &c.HTTP.ReadyMaxLag }),
+
+	durationSetting("SHUTDOWN_TIMEOUT", "how long the ledger in flight may take to finish after SIGTERM", func(c *Config) *time.Duration { return &c.ShutdownTimeout }),
+
+	stringSetting("LOG_LEVEL", "debug, info, warn or error", func(c *Config) *string { return &c.Log.Level }),
+	stringSetting("LOG_FORMAT", "text or json", func(c *Config) *string { return &c.Log.Format }),
+}
+
+func stringSetting(key, usage string, field func(*Config) *string) setting {
+	return setting{
+		key:   key,
+		usage: usage,
+		set:   func(c *Config, val string) error { *field(c) = val; return nil },
+		get:   func(c *Config) string { return *field(c) },
+	}
+}
+
+func secretSetting(key, usage string, field func(*Config) *string) setting {
+	s := stringSetting(key, usage, field)
+	s.secret = true
+	return s
+}
+
+func intSetting(key, usage string, field func(*Config) *int) setting {
+	return setting{
+		key:   key,
+		usage: usage,
+		set: func(c *Config, val string) error {
+			n, err := strconv.Atoi(val)
+			*field(c) = n
+			return err
+		},
+		get: func(c *Config) string { return strconv.Itoa(*field(c)) },
+	}
+}
+
+func uint32Setting(key, usage string, field func(*Config) *uint32) setting {
+	return setting{
+		key:   key,
+		usage: usage,
+		set: func(c *Config, val string) error {
+			n, err := strconv.ParseUint(val, 10, 32)
+			*field(c) = uint32(n)
+			return err
+		},
+		get: func(c *Config) string { return strconv.FormatUint(uint64(*field(c)), 10) },
+	}
+}
+
+func durationSetting(key, usage string, field func(*Config) *time.Duration) setting {
+	return setting{
+		key:   key,
+		usage: usage,
+		set: func(c *Config, val string) error {
+			d, err := time.ParseDuration(val)
+			*field(c) = d
+			return err
+		},
+		get: func(c *Config) string { return field(c).String() },
+	}
+}
+
+func listSetting(key, usage string, field func(*Config) *[]string) setting {
+	return setting{
+		key:   key,
+		usage: usage,
+		set:   func(c *Config, val string) error { *field(c) = splitList(val); return nil },
+		get:   func(c *Config) string { return strings.Join(*field(c), ",") },
+	}
+}
@@ -0,0 +1,881 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"flag"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/celerfi/stellar-indexer-go/config"
+	"github.com/celerfi/stellar-indexer-go/ledgersource"
+	"github.com/stellar/go/ingest/ledgerbackend"
+	"github.com/stellar/go/keypair"
+	"github.com/stellar/go/network"
+	"github.com/stellar/go/strkey"
+	"github.com/stellar/go/xdr"
+)
+
+var regenerateFixtures = flag.Bool("regenerate-fixtures", false, "rewrite the fixture ledgers in testdata/ledgers")
+
+var (
+	captureRPC     = flag.String("capture-rpc", "", "RPC server to capture real ledgers from")
+	captureLedgers = flag.String("capture-ledgers", "", "comma-separated ledger sequences to capture into testdata/ledgers")
+)
+
+// The fixture ledgers are built here rather than captured so every value in
+// them is known. Each covers one handler path; see goldenLedgers.
+const (
+	fixtureSDEXLedger         = 1000
+	fixturePassiveOfferLedger = 1100
+	fixtureOfferLedger        = 1200
+	fixturePoolClaimLedger    = 1300
+	fixtureAquariusLedger     = 2000
+	fixtureForeignTradeLedger = 2100
+	fixtureReflectorLedger    = 3000
+	fixturePathPaymentLedger  = 4000
+	fixturePoolLedger         = 5000
+
+	fixtureProtocol = 22
+)
+
+var (
+	fixtureTrader    = fixtureAccount("trader")
+	fixtureMaker1    = fixtureAccount("maker-1")
+	fixtureMaker2    = fixtureAccount("maker-2")
+	fixtureIssuer    = fixtureAccount("usdc-issuer")
+	fixtureOperator  = fixtureAccount("oracle-operator")
+	fixtureRecipient = fixtureAccount("recipient")
+
+	fixtureUSDC = xdr.MustNewCreditAsset("USDC", fixtureIssuer)
+	fixtureXLM  = xdr.MustNewNativeAsset()
+	fixtureEURT = xdr.MustNewCreditAsset("EURT", fixtureIssuer)
+
+	fixtureUSDCPool = xdr.PoolId(sha256.Sum256([]byte("pool XLM/USDC")))
+	fixtureEURTPool = xdr.PoolId(sha256.Sum256([]byte("pool XLM/EURT")))
+
+	fixtureAquariusPool      = fixtureContract("aquarius-pool")
+	fixtureOtherAMM          = fixtureContract("other-amm")
+	fixtureTokenA            = fixtureContract("token-a")
+	fixtureTokenB            = fixtureContract("token-b")
+	fixtureReflectorContract = fixtureContractAddress("reflector")
+	fixtureReflectorAssets   = []string{"BTC", "ETH", "XLM"}
+)
+
+// TestWriteFixtures regenerates testdata/ledgers when run with
+// -regenerate-fixtures. The golden files must be updated afterwards.
+func TestWriteFixtures(t *testing.T) {
+	if !*regenerateFixtures {
+		t.Skip("run with -regenerate-fixtures to rewrite the fixture ledgers")
+	}
+	ledgers := []xdr.LedgerCloseMeta{sdexFixture(), aquariusFixture(), reflectorFixture(), pathPaymentFixture(), liquidityPoolFixture(),
+		passiveOfferFixture(), offerLifecycleFixture(), poolClaimFixture(), foreignTradeFixture()}
+	if err := os.MkdirAll(ledgerFixtureDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, ledger := range ledgers {
+		raw, err := ledger.MarshalBinary()
+		if err != nil {
+			t.Fatalf("ledger %d: %v", ledger.LedgerSequence(), err)
+		}
+		path := filepath.Join(ledgerFixtureDir, strconv.FormatUint(uint64(ledger.LedgerSequence()), 10)+".xdr")
+		if err := os.WriteFile(path, raw, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// TestCaptureLedgers saves real ledgers from an RPC server into
+// testdata/ledgers, for example:
+//
+//	go test -run TestCaptureLedgers -capture-rpc https://mainnet.sorobanrpc.com -capture-ledgers 52000000,52000001
+//
+// Add each captured ledger to goldenLedgers with its network passphrase and
+// run TestGolden with -update to record its output.
+func TestCaptureLedgers(t *testing.T) {
+	if *captureRPC == "" || *captureLedgers == "" {
+		t.Skip("run with -capture-rpc and -capture-ledgers to save real ledgers")
+	}
+	cfg := config.Default()
+	cfg.Source.Type = ledgersource.SOURCE_RPC
+	cfg.RPC.URL = *captureRPC
+	ctx := context.Background()
+	backend, err := ledgersource.New(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+
+	for _, field := range strings.Split(*captureLedgers, ",") {
+		seq, err := strconv.ParseUint(strings.TrimSpace(field), 10, 32)
+		if err != nil {
+			t.Fatalf("bad ledger %q: %v", field, err)
+		}
+		if err := backend.PrepareRange(ctx, ledgerbackend.BoundedRange(uint32(seq), uint32(seq))); err != nil {
+			t.Fatal(err)
+		}
+		ledger, err := backend.GetLedger(ctx, uint32(seq))
+		if err != nil {
+			t.Fatalf("ledger %d: %v", seq, err)
+		}
+		raw, err := ledger.MarshalBinary()
+		if err != nil {
+			t.Fatalf("ledger %d: %v", seq, err)
+		}
+		path := filepath.Join(ledgerFixtureDir, strconv.FormatUint(seq, 10)+".xdr")
+		if err := os.WriteFile(path, raw, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// sdexTransactions are the order book transactions the SDEX fixtures share:
+// a sell offer that takes two offers and rests with the remainder, a buy
+// offer filled completely, a sell offer that failed underfunded, a passive
+// sell offer that takes one offer and rests, and the cancellation of an
+// offer.
+type sdexTransactions struct {
+	partial, filled, underfunded, passive, cancel fixtureTransaction
+}
+
+func newSDEXTransactions() sdexTransactions {
+	partial := fixtureTx(fixtureTrader, 1, xdr.OperationBody{
+		Type: xdr.OperationTypeManageSellOffer,
+		ManageSellOfferOp: &xdr.ManageSellOfferOp{
+			Selling: fixtureXLM,
+			Buying:  fixtureUSDC,
+			Amount:  1000_0000000,
+			Price:   xdr.Price{N: 1, D: 10},
+		},
+	})
+	partialResult := manageSellResult(xdr.ManageSellOfferResult{
+		Code: xdr.ManageSellOfferResultCodeManageSellOfferSuccess,
+		Success: &xdr.ManageOfferSuccessResult{
+			OffersClaimed: []xdr.ClaimAtom{
+				orderBookClaim(fixtureMaker1, 501, fixtureUSDC, 25_0000000, fixtureXLM, 250_0000000),
+				orderBookClaim(fixtureMaker2, 502, fixtureUSDC, 30_0000000, fixtureXLM, 300_0000000),
+			},
+			Offer: xdr.ManageOfferSuccessResultOffer{
+				Effect: xdr.ManageOfferEffectManageOfferCreated,
+				Offer: &xdr.OfferEntry{
+					SellerId: xdr.MustAddress(fixtureTrader),
+					OfferId:  9001,
+					Selling:  fixtureXLM,
+					Buying:   fixtureUSDC,
+					Amount:   450_0000000,
+					Price:    xdr.Price{N: 1, D: 10},
+				},
+			},
+		},
+	})
+
+	filled := fixtureTx(fixtureTrader, 2, xdr.OperationBody{
+		Type: xdr.OperationTypeManageBuyOffer,
+		ManageBuyOfferOp: &xdr.ManageBuyOfferOp{
+			Selling:   fixtureXLM,
+			Buying:    fixtureUSDC,
+			BuyAmount: 10_0000000,
+			Price:     xdr.Price{N: 10, D: 1},
+		},
+	})
+	filledResult := xdr.OperationResult{
+		Code: xdr.OperationResultCodeOpInner,
+		Tr: &xdr.OperationResultTr{
+			Type: xdr.OperationTypeManageBuyOffer,
+			ManageBuyOfferResult: &xdr.ManageBuyOfferResult{
+				Code: xdr.ManageBuyOfferResultCodeManageBuyOfferSuccess,
+				Success: &xdr.ManageOfferSuccessResult{
+					OffersClaimed: []xdr.ClaimAtom{
+						orderBookClaim(fixtureMaker1, 503, fixtureUSDC, 10_0000000, fixtureXLM, 100_0000000),
+					},
+					Offer: xdr.ManageOfferSuccessResultOffer{Effect: xdr.ManageOfferEffectManageOfferDeleted},
+				},
+			},
+		},
+	}
+
+	underfunded := fixtureTx(fixtureMaker2, 3, xdr.OperationBody{
+		Type: xdr.OperationTypeManageSellOffer,
+		ManageSellOfferOp: &xdr.ManageSellOfferOp{
+			Selling: fixtureUSDC,
+			Buying:  fixtureXLM,
+			Amount:  5000_0000000,
+			Price:   xdr.Price{N: 10, D: 1},
+		},
+	})
+	underfundedResult := manageSellResult(xdr.ManageSellOfferResult{
+		Code: xdr.ManageSellOfferResultCodeManageSellOfferUnderfunded,
+	})
+
+	passive := fixtureTx(fixtureMaker1, 8, xdr.OperationBody{
+		Type: xdr.OperationTypeCreatePassiveSellOffer,
+		CreatePassiveSellOfferOp: &xdr.CreatePassiveSellOfferOp{
+			Selling: fixtureUSDC,
+			Buying:  fixtureXLM,
+			Amount:  50_0000000,
+			Price:   xdr.Price{N: 9, D: 1},
+		},
+	})
+	passiveResult := xdr.OperationResult{
+		Code: xdr.OperationResultCodeOpInner,
+		Tr: &xdr.OperationResultTr{
+			Type: xdr.OperationTypeCreatePassiveSellOffer,
+			CreatePassiveSellOfferResult: &xdr.ManageSellOfferResult{
+				Code: xdr.ManageSellOfferResultCodeManageSellOfferSuccess,
+				Success: &xdr.ManageOfferSuccessResult{
+					OffersClaimed: []xdr.ClaimAtom{
+						orderBookClaim(fixtureTrader, 9001, fixtureXLM, 95_0000000, fixtureUSDC, 10_0000000),
+					},
+					Offer: xdr.ManageOfferSuccessResultOffer{
+						Effect: xdr.ManageOfferEffectManageOfferCreated,
+						Offer: &xdr.OfferEntry{
+							SellerId: xdr.MustAddress(fixtureMaker1),
+							OfferId:  9002,
+							Selling:  fixtureUSDC,
+							Buying:   fixtureXLM,
+							Amount:   40_0000000,
+							Price:    xdr.Price{N: 9, D: 1},
+							Flags:    xdr.Uint32(xdr.OfferEntryFlagsPassiveFlag),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	cancel := fixtureTx(fixtureMaker2, 9, xdr.OperationBody{
+		Type: xdr.OperationTypeManageSellOffer,
+		ManageSellOfferOp: &xdr.ManageSellOfferOp{
+			Selling: fixtureUSDC,
+			Buying:  fixtureXLM,
+			Amount:  0,
+			Price:   xdr.Price{N: 10, D: 1},
+			OfferId: 502,
+		},
+	})
+	cancelResult := manageSellResult(xdr.ManageSellOfferResult{
+		Code: xdr.ManageSellOfferResultCodeManageSellOfferSuccess,
+		Success: &xdr.ManageOfferSuccessResult{
+			Offer: xdr.ManageOfferSuccessResultOffer{Effect: xdr.ManageOfferEffectManageOfferDeleted},
+		},
+	})
+
+	return sdexTransactions{
+		partial:     fixtureTransaction{envelope: partial, code: xdr.TransactionResultCodeTxSuccess, results: []xdr.OperationResult{partialResult}},
+		filled:      fixtureTransaction{envelope: filled, code: xdr.TransactionResultCodeTxSuccess, results: []xdr.OperationResult{filledResult}},
+		underfunded: fixtureTransaction{envelope: underfunded, code: xdr.TransactionResultCodeTxFailed, results: []xdr.OperationResult{underfundedResult}},
+		passive:     fixtureTransaction{envelope: passive, code: xdr.TransactionResultCodeTxSuccess, results: []xdr.OperationResult{passiveResult}},
+		cancel:      fixtureTransaction{envelope: cancel, code: xdr.TransactionResultCodeTxSuccess, results: []xdr.OperationResult{cancelResult}},
+	}
+}
+
+// sdexFixture holds the partially filled, filled and underfunded offers.
+func sdexFixture() xdr.LedgerCloseMeta {
+	txs := newSDEXTransactions()
+	return fixtureLedger(fixtureSDEXLedger, []fixtureTransaction{txs.partial, txs.filled, txs.underfunded})
+}
+
+// passiveOfferFixture holds the passive sell offer.
+func passiveOfferFixture() xdr.LedgerCloseMeta {
+	return fixtureLedger(fixturePassiveOfferLedger, []fixtureTransaction{newSDEXTransactions().passive})
+}
+
+// offerLifecycleFixture replays the SDEX transactions with the offer entry
+// changes they make: offers created, partially taken, consumed, cancelled
+// and created passive.
+func offerLifecycleFixture() xdr.LedgerCloseMeta {
+	seq := uint32(fixtureOfferLedger)
+	offer501 := offerEntry(fixtureMaker1, 501, fixtureUSDC, fixtureXLM, 25_0000000, xdr.Price{N: 10, D: 1}, 0)
+	offer502 := offerEntry(fixtureMaker2, 502, fixtureUSDC, fixtureXLM, 80_0000000, xdr.Price{N: 10, D: 1}, 0)
+	offer502Left := offerEntry(fixtureMaker2, 502, fixtureUSDC, fixtureXLM, 50_0000000, xdr.Price{N: 10, D: 1}, seq)
+	offer503 := offerEntry(fixtureMaker1, 503, fixtureUSDC, fixtureXLM, 10_0000000, xdr.Price{N: 10, D: 1}, 0)
+	offer9001 := offerEntry(fixtureTrader, 9001, fixtureXLM, fixtureUSDC, 450_0000000, xdr.Price{N: 1, D: 10}, seq)
+	offer9001Left := offerEntry(fixtureTrader, 9001, fixtureXLM, fixtureUSDC, 355_0000000, xdr.Price{N: 1, D: 10}, seq)
+	offer9002 := offerEntry(fixtureMaker1, 9002, fixtureUSDC, fixtureXLM, 40_0000000, xdr.Price{N: 9, D: 1}, seq)
+	offer9002.Data.Offer.Flags = xdr.Uint32(xdr.OfferEntryFlagsPassiveFlag)
+
+	txs := newSDEXTransactions()
+	txs.partial.changes = []xdr.LedgerEntryChanges{concatChanges(removed(offer501), updated(offer502, offer502Left), created(offer9001))}
+	txs.filled.changes = []xdr.LedgerEntryChanges{removed(offer503)}
+	txs.passive.changes = []xdr.LedgerEntryChanges{concatChanges(updated(offer9001, offer9001Left), created(offer9002))}
+	txs.cancel.changes = []xdr.LedgerEntryChanges{removed(offer502Left)}
+	return fixtureLedger(seq, []fixtureTransaction{txs.partial, txs.filled, txs.passive, txs.cancel})
+}
+
+// poolClaimFixture holds a manage buy and a manage sell offer that each take
+// an offer and then swap against a liquidity pool, so their claims mix
+// order book and pool atoms.
+func poolClaimFixture() xdr.LedgerCloseMeta {
+	buy := fixtureTx(fixtureTrader, 13, xdr.OperationBody{
+		Type: xdr.OperationTypeManageBuyOffer,
+		ManageBuyOfferOp: &xdr.ManageBuyOfferOp{
+			Selling:   fixtureXLM,
+			Buying:    fixtureEURT,
+			BuyAmount: 15_0000000,
+			Price:     xdr.Price{N: 11, D: 1},
+		},
+	})
+	buyResult := xdr.OperationResult{
+		Code: xdr.OperationResultCodeOpInner,
+		Tr: &xdr.OperationResultTr{
+			Type: xdr.OperationTypeManageBuyOffer,
+			ManageBuyOfferResult: &xdr.ManageBuyOfferResult{
+				Code: xdr.ManageBuyOfferResultCodeManageBuyOfferSuccess,
+				Success: &xdr.ManageOfferSuccessResult{
+					OffersClaimed: []xdr.ClaimAtom{
+						orderBookClaim(fixtureMaker1, 504, fixtureEURT, 5_0000000, fixtureXLM, 50_0000000),
+						poolClaim(fixtureEURTPool, fixtureEURT, 10_0000000, fixtureXLM, 105_0000000),
+					},
+					Offer: xdr.ManageOfferSuccessResultOffer{Effect: xdr.ManageOfferEffectManageOfferDeleted},
+				},
+			},
+		},
+	}
+
+	sell := fixtureTx(fixtureMaker2, 14, xdr.OperationBody{
+		Type: xdr.OperationTypeManageSellOffer,
+		ManageSellOfferOp: &xdr.ManageSellOfferOp{
+			Selling: fixtureUSDC,
+			Buying:  fixtureXLM,
+			Amount:  20_0000000,
+			Price:   xdr.Price{N: 8, D: 1},
+		},
+	})
+	sellResult := manageSellResult(xdr.ManageSellOfferResult{
+		Code: xdr.ManageSellOfferResultCodeManageSellOfferSuccess,
+		Success: &xdr.ManageOfferSuccessResult{
+			OffersClaimed: []xdr.ClaimAtom{
+				poolClaim(fixtureUSDCPool, fixtureXLM, 90_0000000, fixtureUSDC, 10_0000000),
+				orderBookClaim(fixtureTrader, 505, fixtureXLM, 85_0000000, fixtureUSDC, 10_0000000),
+			},
+			Offer: xdr.ManageOfferSuccessResultOffer{Effect: xdr.ManageOfferEffectManageOfferDeleted},
+		},
+	})
+
+	return fixtureLedger(fixturePoolClaimLedger, []fixtureTransaction{
+		{envelope: buy, code: xdr.TransactionResultCodeTxSuccess, results: []xdr.OperationResult{buyResult}},
+		{envelope: sell, code: xdr.TransactionResultCodeTxSuccess, results: []xdr.OperationResult{sellResult}},
+	})
+}
+
+// aquariusFixture holds a swap through an Aquarius pool that emitted a
+// trade event.
+func aquariusFixture() xdr.LedgerCloseMeta {
+	swap := fixtureSorobanTx(fixtureTrader, 4, fixtureAquariusPool, "swap", xdr.ScVec{})
+	event := xdr.ContractEvent{
+		ContractId: &fixtureAquariusPool,
+		Type:       xdr.ContractEventTypeContract,
+		Body: xdr.ContractEventBody{
+			V: 0,
+			V0: &xdr.ContractEventV0{
+				Topics: xdr.ScVec{
+					symbolVal("trade"),
+					contractVal(fixtureTokenA),
+					contractVal(fixtureTokenB),
+				},
+				Data: vecVal(i128Val(120_0000000), i128Val(59_8200000), i128Val(3600000)),
+			},
+		},
+	}
+	return fixtureLedger(fixtureAquariusLedger, []fixtureTransaction{
+		{envelope: swap, code: xdr.TransactionResultCodeTxSuccess, results: []xdr.OperationResult{invokeSuccess()}, events: []xdr.ContractEvent{event}},
+	})
+}
+
+// foreignTradeFixture holds a swap through an Aquarius pool that routes part
+// of the trade through another AMM, which emits a trade event of its own.
+func foreignTradeFixture() xdr.LedgerCloseMeta {
+	swap := fixtureSorobanTx(fixtureTrader, 15, fixtureAquariusPool, "swap", xdr.ScVec{})
+	tradeEvent := func(contract xdr.ContractId, amountIn, amountOut, fee int64) xdr.ContractEvent {
+		return xdr.ContractEvent{
+			ContractId: &contract,
+			Type:       xdr.ContractEventTypeContract,
+			Body: xdr.ContractEventBody{
+				V: 0,
+				V0: &xdr.ContractEventV0{
+					Topics: xdr.ScVec{
+						symbolVal("trade"),
+						contractVal(fixtureTokenA),
+						contractVal(fixtureTokenB),
+					},
+					Data: vecVal(i128Val(amountIn), i128Val(amountOut), i128Val(fee)),
+				},
+			},
+		}
+	}
+	return fixtureLedger(fixtureForeignTradeLedger, []fixtureTransaction{
+		{envelope: swap, code: xdr.TransactionResultCodeTxSuccess, results: []xdr.OperationResult{invokeSuccess()}, events: []xdr.ContractEvent{
+			tradeEvent(fixtureOtherAMM, 40_0000000, 19_9000000, 1200000),
+			tradeEvent(fixtureAquariusPool, 80_0000000, 39_8800000, 2400000),
+		}},
+	})
+}
+
+// reflectorFixture holds a set_price update of the Reflector oracle with a
+// zero price for one asset, which is skipped.
+func reflectorFixture() xdr.LedgerCloseMeta {
+	contractID := fixtureContract("reflector")
+	const decimals = 100_000_000_000_000 // 10^14
+	prices := vecVal(
+		i128Val(64_250*decimals),
+		i128Val(0),
+		i128Val(12*decimals/100),
+	)
+	timestamp := uint64(time.Date(2025, 1, 2, 3, 4, 0, 0, time.UTC).UnixMilli())
+	update := fixtureSorobanTx(fixtureOperator, 5, contractID, "set_price", xdr.ScVec{prices, u64Val(timestamp)})
+	return fixtureLedger(fixtureReflectorLedger, []fixtureTransaction{
+		{envelope: update, code: xdr.TransactionResultCodeTxSuccess, results: []xdr.OperationResult{invokeSuccess()}},
+	})
+}
+
+// pathPaymentFixture holds a strict send that crosses an offer and a pool,
+// and a strict receive through XLM that crosses an offer and then a pool.
+func pathPaymentFixture() xdr.LedgerCloseMeta {
+	strictSend := fixtureTx(fixtureTrader, 6, xdr.OperationBody{
+		Type: xdr.OperationTypePathPaymentStrictSend,
+		PathPaymentStrictSendOp: &xdr.PathPaymentStrictSendOp{
+			SendAsset:   fixtureXLM,
+			SendAmount:  100_0000000,
+			Destination: xdr.MustMuxedAddress(fixtureRecipient),
+			DestAsset:   fixtureUSDC,
+			DestMin:     9_5000000,
+		},
+	})
+	strictSendResult := xdr.OperationResult{
+		Code: xdr.OperationResultCodeOpInner,
+		Tr: &xdr.OperationResultTr{
+			Type: xdr.OperationTypePathPaymentStrictSend,
+			PathPaymentStrictSendResult: &xdr.PathPaymentStrictSendResult{
+				Code: xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendSuccess,
+				Success: &xdr.PathPaymentStrictSendResultSuccess{
+					Offers: []xdr.ClaimAtom{
+						orderBookClaim(fixtureMaker1, 504, fixtureUSDC, 5_0000000, fixtureXLM, 50_0000000),
+						poolClaim(fixtureUSDCPool, fixtureUSDC, 4_9000000, fixtureXLM, 50_0000000),
+					},
+					Last: xdr.SimplePaymentResult{
+						Destination: xdr.MustAddress(fixtureRecipient),
+						Asset:       fixtureUSDC,
+						Amount:      9_9000000,
+					},
+				},
+			},
+		},
+	}
+
+	strictReceive := fixtureTx(fixtureTrader, 7, xdr.OperationBody{
+		Type: xdr.OperationTypePathPaymentStrictReceive,
+		PathPaymentStrictReceiveOp: &xdr.PathPaymentStrictReceiveOp{
+			SendAsset:   fixtureUSDC,
+			SendMax:     25_0000000,
+			Destination: xdr.MustMuxedAddress(fixtureRecipient),
+			DestAsset:   fixtureEURT,
+			DestAmount:  20_0000000,
+			Path:        []xdr.Asset{fixtureXLM},
+		},
+	})
+	strictReceiveResult := xdr.OperationResult{
+		Code: xdr.OperationResultCodeOpInner,
+		Tr: &xdr.OperationResultTr{
+			Type: xdr.OperationTypePathPaymentStrictReceive,
+			PathPaymentStrictReceiveResult: &xdr.PathPaymentStrictReceiveResult{
+				Code: xdr.PathPaymentStrictReceiveResultCodePathPaymentStrictReceiveSuccess,
+				Success: &xdr.PathPaymentStrictReceiveResultSuccess{
+					Offers: []xdr.ClaimAtom{
+						orderBookClaim(fixtureMaker2, 505, fixtureXLM, 200_0000000, fixtureUSDC, 21_0000000),
+						poolClaim(fixtureEURTPool, fixtureEURT, 20_0000000, fixtureXLM, 200_0000000),
+					},
+					Last: xdr.SimplePaymentResult{
+						Destination: xdr.MustAddress(fixtureRecipient),
+						Asset:       fixtureEURT,
+						Amount:      20_0000000,
+					},
+				},
+			},
+		},
+	}
+
+	return fixtureLedger(fixturePathPaymentLedger, []fixtureTransaction{
+		{envelope: strictSend, code: xdr.TransactionResultCodeTxSuccess, results: []xdr.OperationResult{strictSendResult}},
+		{envelope: strictReceive, code: xdr.TransactionResultCodeTxSuccess, results: []xdr.OperationResult{strictReceiveResult}},
+	})
+}
+
+// liquidityPoolFixture holds a deposit into the XLM/USDC pool, a sell offer
+// filled by swapping against that pool and a withdrawal from the XLM/EURT
+// pool, with the pool entry changes of each.
+func liquidityPoolFixture() xdr.LedgerCloseMeta {
+	deposit := fixtureTx(fixtureTrader, 10, xdr.OperationBody{
+		Type: xdr.OperationTypeLiquidityPoolDeposit,
+		LiquidityPoolDepositOp: &xdr.LiquidityPoolDepositOp{
+			LiquidityPoolId: fixtureUSDCPool,
+			MaxAmountA:      100_0000000,
+			MaxAmountB:      12_0000000,
+			MinPrice:        xdr.Price{N: 9, D: 1},
+			MaxPrice:        xdr.Price{N: 11, D: 1},
+		},
+	})
+	depositResult := xdr.OperationResult{
+		Code: xdr.OperationResultCodeOpInner,
+		Tr: &xdr.OperationResultTr{
+			Type:                       xdr.OperationTypeLiquidityPoolDeposit,
+			LiquidityPoolDepositResult: &xdr.LiquidityPoolDepositResult{Code: xdr.LiquidityPoolDepositResultCodeLiquidityPoolDepositSuccess},
+		},
+	}
+
+	swap := fixtureTx(fixtureMaker1, 11, xdr.OperationBody{
+		Type: xdr.OperationTypeManageSellOffer,
+		ManageSellOfferOp: &xdr.ManageSellOfferOp{
+			Selling: fixtureUSDC,
+			Buying:  fixtureXLM,
+			Amount:  10_0000000,
+			Price:   xdr.Price{N: 8, D: 1},
+		},
+	})
+	swapResult := manageSellResult(xdr.ManageSellOfferResult{
+		Code: xdr.ManageSellOfferResultCodeManageSellOfferSuccess,
+		Success: &xdr.ManageOfferSuccessResult{
+			OffersClaimed: []xdr.ClaimAtom{
+				poolClaim(fixtureUSDCPool, fixtureXLM, 90_0000000, fixtureUSDC, 10_0000000),
+			},
+			Offer: xdr.ManageOfferSuccessResultOffer{Effect: xdr.ManageOfferEffectManageOfferDeleted},
+		},
+	})
+
+	withdraw := fixtureTx(fixtureMaker2, 12, xdr.OperationBody{
+		Type: xdr.OperationTypeLiquidityPoolWithdraw,
+		LiquidityPoolWithdrawOp: &xdr.LiquidityPoolWithdrawOp{
+			LiquidityPoolId: fixtureEURTPool,
+			Amount:          10_0000000,
+			MinAmountA:      30_0000000,
+			MinAmountB:      3_0000000,
+		},
+	})
+	withdrawResult := xdr.OperationResult{
+		Code: xdr.OperationResultCodeOpInner,
+		Tr: &xdr.OperationResultTr{
+			Type:                        xdr.OperationTypeLiquidityPoolWithdraw,
+			LiquidityPoolWithdrawResult: &xdr.LiquidityPoolWithdrawResult{Code: xdr.LiquidityPoolWithdrawResultCodeLiquidityPoolWithdrawSuccess},
+		},
+	}
+
+	usdcPool := poolEntry(fixtureUSDCPool, fixtureXLM, fixtureUSDC, 1000_0000000, 100_0000000, 316_2277660)
+	usdcPoolDeposited := poolEntry(fixtureUSDCPool, fixtureXLM, fixtureUSDC, 1100_0000000, 110_0000000, 347_8505426)
+	usdcPoolSwapped := poolEntry(fixtureUSDCPool, fixtureXLM, fixtureUSDC, 1010_0000000, 120_0000000, 347_8505426)
+	eurtPool := poolEntry(fixtureEURTPool, fixtureXLM, fixtureEURT, 2000_0000000, 200_0000000, 632_4555320)
+	eurtPoolWithdrawn := poolEntry(fixtureEURTPool, fixtureXLM, fixtureEURT, 1968_3772234, 196_8377224, 622_4555320)
+
+	return fixtureLedger(fixturePoolLedger, []fixtureTransaction{
+		{envelope: deposit, code: xdr.TransactionResultCodeTxSuccess, results: []xdr.OperationResult{depositResult},
+			changes: []xdr.LedgerEntryChanges{updated(usdcPool, usdcPoolDeposited)}},
+		{envelope: swap, code: xdr.TransactionResultCodeTxSuccess, results: []xdr.OperationResult{swapResult},
+			changes: []xdr.LedgerEntryChanges{updated(usdcPoolDeposited, usdcPoolSwapped)}},
+		{envelope: withdraw, code: xdr.TransactionResultCodeTxSuccess, results: []xdr.OperationResult{withdrawResult},
+			changes: []xdr.LedgerEntryChanges{updated(eurtPool, eurtPoolWithdrawn)}},
+	})
+}
+
+type fixtureTransaction struct {
+	envelope xdr.TransactionEnvelope
+	code     xdr.TransactionResultCode
+	results  []xdr.OperationResult
+	events   []xdr.ContractEvent
+	changes  []xdr.LedgerEntryChanges // per operation
+}
+
+// fixtureLedger wraps txs in a protocol 22 LedgerCloseMeta closed one
+// second per ledger after 2025-01-01.
+func fixtureLedger(seq uint32, txs []fixtureTransaction) xdr.LedgerCloseMeta {
+	var envelopes []xdr.TransactionEnvelope
+	var processing []xdr.TransactionResultMeta
+	for _, tx := range txs {
+		hash, err := network.HashTransactionInEnvelope(tx.envelope, network.TestNetworkPassphrase)
+		if err != nil {
+			panic(err)
+		}
+		results := tx.results
+		meta := xdr.TransactionMetaV3{Operations: make([]xdr.OperationMeta, len(tx.envelope.Operations()))}
+		for i, changes := range tx.changes {
+			meta.Operations[i].Changes = changes
+		}
+		if tx.envelope.V1.Tx.Ext.V == 1 {
+			meta.SorobanMeta = &xdr.SorobanTransactionMeta{
+				Ext: xdr.SorobanTransactionMetaExt{
+					V: 1,
+					V1: &xdr.SorobanTransactionMetaExtV1{
+						TotalNonRefundableResourceFeeCharged: 60_000,
+						TotalRefundableResourceFeeCharged:    15_000,
+						RentFeeCharged:                       5_000,
+					},
+				},
+				Events:      tx.events,
+				ReturnValue: xdr.ScVal{Type: xdr.ScValTypeScvVoid},
+			}
+		}
+
+		envelopes = append(envelopes, tx.envelope)
+		processing = append(processing, xdr.TransactionResultMeta{
+			Result: xdr.TransactionResultPair{
+				TransactionHash: xdr.Hash(hash),
+				Result: xdr.TransactionResult{
+					FeeCharged: xdr.Int64(tx.envelope.Fee()),
+					Result:     xdr.TransactionResultResult{Code: tx.code, Results: &results},
+				},
+			},
+			TxApplyProcessing: xdr.TransactionMeta{V: 3, V3: &meta},
+		})
+	}
+
+	previous := xdr.Hash(sha256.Sum256([]byte("ledger " + strconv.Itoa(int(seq)-1))))
+	closeTime := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(seq) * time.Second)
+	return xdr.LedgerCloseMeta{
+		V: 1,
+		V1: &xdr.LedgerCloseMetaV1{
+			LedgerHeader: xdr.LedgerHeaderHistoryEntry{
+				Hash: xdr.Hash(sha256.Sum256([]byte("ledger " + strconv.Itoa(int(seq))))),
+				Header: xdr.LedgerHeader{
+					LedgerVersion:      fixtureProtocol,
+					PreviousLedgerHash: previous,
+					ScpValue: xdr.StellarValue{
+						CloseTime: xdr.TimePoint(closeTime.Unix()),
+						Ext:       xdr.StellarValueExt{V: xdr.StellarValueTypeStellarValueBasic},
+					},
+					LedgerSeq:    xdr.Uint32(seq),
+					TotalCoins:   1_054_439_020_873_472_865,
+					FeePool:      42_000_000_000,
+					BaseFee:      100,
+					BaseReserve:  5_000_000,
+					MaxTxSetSize: 1000,
+				},
+			},
+			TxSet: xdr.GeneralizedTransactionSet{
+				V: 1,
+				V1TxSet: &xdr.TransactionSetV1{
+					PreviousLedgerHash: previous,
+					Phases: []xdr.TransactionPhase{{
+						V: 0,
+						V0Components: &[]xdr.TxSetComponent{{
+							Type:                  xdr.TxSetComponentTypeTxsetCompTxsMaybeDiscountedFee,
+							TxsMaybeDiscountedFee: &xdr.TxSetComponentTxsMaybeDiscountedFee{Txs: envelopes},
+						}},
+					}},
+				},
+			},
+			TxProcessing: processing,
+		},
+	}
+}
+
+func fixtureTx(source string, seq int64, body xdr.OperationBody) xdr.TransactionEnvelope {
+	return xdr.TransactionEnvelope{
+		Type: xdr.EnvelopeTypeEnvelopeTypeTx,
+		V1: &xdr.TransactionV1Envelope{
+			Tx: xdr.Transaction{
+				SourceAccount: xdr.MustMuxedAddress(source),
+				Fee:           100,
+				SeqNum:        xdr.SequenceNumber(seq),
+				Cond:          xdr.Preconditions{Type: xdr.PreconditionTypePrecondNone},
+				Memo:          xdr.Memo{Type: xdr.MemoTypeMemoNone},
+				Operations:    []xdr.Operation{{Body: body}},
+			},
+		},
+	}
+}
+
+func fixtureSorobanTx(source string, seq int64, contract xdr.ContractId, function string, args xdr.ScVec) xdr.TransactionEnvelope {
+	envelope := fixtureTx(source, seq, xdr.OperationBody{
+		Type: xdr.OperationTypeInvokeHostFunction,
+		InvokeHostFunctionOp: &xdr.InvokeHostFunctionOp{
+			HostFunction: xdr.HostFunction{
+				Type: xdr.HostFunctionTypeHostFunctionTypeInvokeContract,
+				InvokeContract: &xdr.InvokeContractArgs{
+					ContractAddress: xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeContract, ContractId: &contract},
+					FunctionName:    xdr.ScSymbol(function),
+					Args:            args,
+				},
+			},
+		},
+	})
+	envelope.V1.Tx.Fee = 100_000
+	envelope.V1.Tx.Ext = xdr.TransactionExt{
+		V: 1,
+		SorobanData: &xdr.SorobanTransactionData{
+			Resources:   xdr.SorobanResources{Instructions: 2_000_000, DiskReadBytes: 4096, WriteBytes: 1024},
+			ResourceFee: 80_000,
+		},
+	}
+	return envelope
+}
+
+func manageSellResult(result xdr.ManageSellOfferResult) xdr.OperationResult {
+	return xdr.OperationResult{
+		Code: xdr.OperationResultCodeOpInner,
+		Tr:   &xdr.OperationResultTr{Type: xdr.OperationTypeManageSellOffer, ManageSellOfferResult: &result},
+	}
+}
+
+func invokeSuccess() xdr.OperationResult {
+	success := xdr.Hash{}
+	return xdr.OperationResult{
+		Code: xdr.OperationResultCodeOpInner,
+		Tr: &xdr.OperationResultTr{
+			Type: xdr.OperationTypeInvokeHostFunction,
+			InvokeHostFunctionResult: &xdr.InvokeHostFunctionResult{
+				Code:    xdr.InvokeHostFunctionResultCodeInvokeHostFunctionSuccess,
+				Success: &success,
+			},
+		},
+	}
+}
+
+func orderBookClaim(seller string, offerID int64, sold xdr.Asset, amountSold int64, bought xdr.Asset, amountBought int64) xdr.ClaimAtom {
+	return xdr.ClaimAtom{
+		Type: xdr.ClaimAtomTypeClaimAtomTypeOrderBook,
+		OrderBook: &xdr.ClaimOfferAtom{
+			SellerId:     xdr.MustAddress(seller),
+			OfferId:      xdr.Int64(offerID),
+			AssetSold:    sold,
+			AmountSold:   xdr.Int64(amountSold),
+			AssetBought:  bought,
+			AmountBought: xdr.Int64(amountBought),
+		},
+	}
+}
+
+func offerEntry(seller string, offerID int64, selling, buying xdr.Asset, amount int64, price xdr.Price, lastModified uint32) xdr.LedgerEntry {
+	if lastModified == 0 {
+		lastModified = fixtureOfferLedger - 100
+	}
+	return xdr.LedgerEntry{
+		LastModifiedLedgerSeq: xdr.Uint32(lastModified),
+		Data: xdr.LedgerEntryData{
+			Type: xdr.LedgerEntryTypeOffer,
+			Offer: &xdr.OfferEntry{
+				SellerId: xdr.MustAddress(seller),
+				OfferId:  xdr.Int64(offerID),
+				Selling:  selling,
+				Buying:   buying,
+				Amount:   xdr.Int64(amount),
+				Price:    price,
+			},
+		},
+	}
+}
+
+func created(entry xdr.LedgerEntry) xdr.LedgerEntryChanges {
+	return xdr.LedgerEntryChanges{{Type: xdr.LedgerEntryChangeTypeLedgerEntryCreated, Created: &entry}}
+}
+
+func updated(before, after xdr.LedgerEntry) xdr.LedgerEntryChanges {
+	return xdr.LedgerEntryChanges{
+		{Type: xdr.LedgerEntryChangeTypeLedgerEntryState, State: &before},
+		{Type: xdr.LedgerEntryChangeTypeLedgerEntryUpdated, Updated: &after},
+	}
+}
+
+func removed(entry xdr.LedgerEntry) xdr.LedgerEntryChanges {
+	key, err := entry.LedgerKey()
+	if err != nil {
+		panic(err)
+	}
+	return xdr.LedgerEntryChanges{
+		{Type: xdr.LedgerEntryChangeTypeLedgerEntryState, State: &entry},
+		{Type: xdr.LedgerEntryChangeTypeLedgerEntryRemoved, Removed: &key},
+	}
+}
+
+func concatChanges(changes ...xdr.LedgerEntryChanges) xdr.LedgerEntryChanges {
+	var all xdr.LedgerEntryChanges
+	for _, c := range changes {
+		all = append(all, c...)
+	}
+	return all
+}
+
+func poolEntry(pool xdr.PoolId, assetA, assetB xdr.Asset, reserveA, reserveB, shares int64) xdr.LedgerEntry {
+	return xdr.LedgerEntry{
+		LastModifiedLedgerSeq: fixturePoolLedger - 100,
+		Data: xdr.LedgerEntryData{
+			Type: xdr.LedgerEntryTypeLiquidityPool,
+			LiquidityPool: &xdr.LiquidityPoolEntry{
+				LiquidityPoolId: pool,
+				Body: xdr.LiquidityPoolEntryBody{
+					Type: xdr.LiquidityPoolTypeLiquidityPoolConstantProduct,
+					ConstantProduct: &xdr.LiquidityPoolEntryConstantProduct{
+						Params: xdr.LiquidityPoolConstantProductParameters{
+							AssetA: assetA,
+							AssetB: assetB,
+							Fee:    xdr.LiquidityPoolFeeV18,
+						},
+						ReserveA:                 xdr.Int64(reserveA),
+						ReserveB:                 xdr.Int64(reserveB),
+						TotalPoolShares:          xdr.Int64(shares),
+						PoolSharesTrustLineCount: 2,
+					},
+				},
+			},
+		},
+	}
+}
+
+func poolClaim(pool xdr.PoolId, sold xdr.Asset, amountSold int64, bought xdr.Asset, amountBought int64) xdr.ClaimAtom {
+	return xdr.ClaimAtom{
+		Type: xdr.ClaimAtomTypeClaimAtomTypeLiquidityPool,
+		LiquidityPool: &xdr.ClaimLiquidityAtom{
+			LiquidityPoolId: pool,
+			AssetSold:       sold,
+			AmountSold:      xdr.Int64(amountSold),
+			AssetBought:     bought,
+			AmountBought:    xdr.Int64(amountBought),
+		},
+	}
+}
+
+func fixtureAccount(name string) string {
+	kp, err := keypair.FromRawSeed(sha256.Sum256([]byte(name)))
+	if err != nil {
+		panic(err)
+	}
+	return kp.Address()
+}
+
+func fixtureContract(name string) xdr.ContractId {
+	return xdr.ContractId(sha256.Sum256([]byte("contract " + name)))
+}
+
+func fixtureContractAddress(name string) string {
+	id := fixtureContract(name)
+	return strkey.MustEncode(strkey.VersionByteContract, id[:])
+}
+
+func symbolVal(s string) xdr.ScVal {
+	sym := xdr.ScSymbol(s)
+	return xdr.ScVal{Type: xdr.ScValTypeScvSymbol, Sym: &sym}
+}
+
+func contractVal(id xdr.ContractId) xdr.ScVal {
+	address := xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeContract, ContractId: &id}
+	return xdr.ScVal{Type: xdr.ScValTypeScvAddress, Address: &address}
+}
+
+func i128Val(n int64) xdr.ScVal {
+	parts := xdr.Int128Parts{Lo: xdr.Uint64(n)}
+	if n < 0 {
+		parts.Hi = -1
+	}
+	return xdr.ScVal{Type: xdr.ScValTypeScvI128, I128: &parts}
+}
+
+func u64Val(n uint64) xdr.ScVal {
+	v := xdr.Uint64(n)
+	return xdr.ScVal{Type: xdr.ScValTypeScvU64, U64: &v}
+}
+
+func vecVal(items ...xdr.ScVal) xdr.ScVal {
+	vec := xdr.ScVec(items)
+	ptr := &vec
+	return xdr.ScVal{Type: xdr.ScValTypeScvVec, Vec: &ptr}
+}
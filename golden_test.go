@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/celerfi/stellar-indexer-go/config"
+	tx_handlers "github.com/celerfi/stellar-indexer-go/handlers"
+	"github.com/celerfi/stellar-indexer-go/ledgersource"
+	"github.com/celerfi/stellar-indexer-go/pipeline"
+	"github.com/celerfi/stellar-indexer-go/sink"
+	"github.com/celerfi/stellar-indexer-go/utils"
+	"github.com/stellar/go/ingest/ledgerbackend"
+	"github.com/stellar/go/network"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files with the current output")
+
+const (
+	ledgerFixtureDir = "testdata/ledgers"
+	goldenDir        = "testdata/golden"
+)
+
+// goldenLedgers names the ledgers in testdata/ledgers, stored as <seq>.xdr
+// so they can also be replayed with LEDGER_SOURCE=file. Each feature adds
+// its own ledger rather than changing the ones already there. Ledgers
+// captured from a real network with TestCaptureLedgers give their
+// network's passphrase; the synthetic fixtures use the test network's.
+var goldenLedgers = []struct {
+	name       string
+	seq        uint32
+	passphrase string
+}{
+	{"sdex_partial_fills", fixtureSDEXLedger, ""},
+	{"passive_offer", fixturePassiveOfferLedger, ""},
+	{"offer_lifecycle", fixtureOfferLedger, ""},
+	{"sdex_pool_claims", fixturePoolClaimLedger, ""},
+	{"aquarius_trade", fixtureAquariusLedger, ""},
+	{"aquarius_foreign_trade", fixtureForeignTradeLedger, ""},
+	{"reflector_set_price", fixtureReflectorLedger, ""},
+	{"path_payments", fixturePathPaymentLedger, ""},
+	{"liquidity_pools", fixturePoolLedger, ""},
+}
+
+// TestGolden runs every fixture ledger through the enabled processors into
+// an in-memory sink and compares the rows with testdata/golden. Run with
+// -update to accept a change in output.
+func TestGolden(t *testing.T) {
+	// Token lookups fail fast instead of reaching out to a real network.
+	unavailable := httptest.NewServer(http.NotFoundHandler())
+	defer unavailable.Close()
+	cfg := config.Default()
+	cfg.RPC.URL = unavailable.URL
+	cfg.Network.HorizonURL = unavailable.URL
+	utils.SetTokenConfig(cfg)
+	tx_handlers.SetReflectorAssets(map[string][]string{fixtureReflectorContract: fixtureReflectorAssets})
+	tx_handlers.SetAquariusContracts(fixtureContractAddress("aquarius-pool"))
+
+	registry, err := newRegistry(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := pipeline.NewPool(2, 8)
+	defer pool.Close()
+	backend, err := ledgersource.NewFileBackend(ledgerFixtureDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+
+	for _, fixture := range goldenLedgers {
+		t.Run(fixture.name, func(t *testing.T) {
+			ctx := context.Background()
+			if err := backend.PrepareRange(ctx, ledgerbackend.BoundedRange(fixture.seq, fixture.seq)); err != nil {
+				t.Fatal(err)
+			}
+			ledger, err := backend.GetLedger(ctx, fixture.seq)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			passphrase := fixture.passphrase
+			if passphrase == "" {
+				passphrase = network.TestNetworkPassphrase
+			}
+			out := sink.NewMemory()
+			if err := indexLedger(ctx, out, pool, registry, passphrase, "golden", ledger); err != nil {
+				t.Fatal(err)
+			}
+			batches := out.Batches()
+			if len(batches) != 1 {
+				t.Fatalf("got %d batches, want 1 (dead letters: %v)", len(batches), out.DeadLetters())
+			}
+			// Token and pool rows are left out: they depend on lookups
+			// against the network and on what the process saw before.
+			batch := batches[0]
+			batch.Tokens, batch.Pools = nil, nil
+			got, err := json.MarshalIndent(batch, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			path := filepath.Join(goldenDir, fixture.name+".json")
+			if *update {
+				if err := os.MkdirAll(goldenDir, 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("%v (run go test -run TestGolden -update to create it)", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("output differs from %s; run go test -run TestGolden -update to accept it\n--- got\n%s", path, got)
+			}
+		})
+	}
+}
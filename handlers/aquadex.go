@@ -1,44 +1,146 @@
 package tx_handlers
 
 import (
-	"fmt"
+	"context"
+	"log/slog"
 	"time"
 
+	"github.com/celerfi/stellar-indexer-go/logging"
 	"github.com/celerfi/stellar-indexer-go/models"
 	"github.com/celerfi/stellar-indexer-go/utils"
 	"github.com/stellar/go/ingest"
 	"github.com/stellar/go/xdr"
 )
 
-func ProcessAquariusTransaction(tx ingest.LedgerTransaction, seq uint32, blocktime time.Time) {
-	fmt.Println("found aquarius tx")
-	var tx_array []models.TransactionModels
+func init() {
+	Register(aquariusProcessor{})
+}
+
+// aquariusContracts holds the Aquarius contracts of the active network profile.
+var aquariusContracts = map[string]bool{}
+
+// SetAquariusContracts tracks the given Aquarius contracts, skipping empty
+// ones. Call this once before starting the ledger stream.
+func SetAquariusContracts(contracts ...string) {
+	aquariusContracts = map[string]bool{}
+	for _, contract := range contracts {
+		if contract != "" {
+			aquariusContracts[contract] = true
+		}
+	}
+	if len(aquariusContracts) == 0 {
+		slog.Warn("No Aquarius contracts configured for the network, Aquarius trades will not be indexed")
+	}
+}
+
+// aquariusProcessor indexes the "trade" events emitted by the Aquarius
+// contracts in transactions that invoke one of them, at the top level or in
+// an authorized sub-invocation. Trade events of other contracts in the same
+// transaction are skipped, and Reflector invocations are left to the
+// reflector processor.
+type aquariusProcessor struct{}
+
+func (aquariusProcessor) Name() string { return "aquarius" }
+
+func (aquariusProcessor) Scopes() []models.RowScope {
+	return []models.RowScope{
+		{Table: "transaction_models", LedgerColumn: "ledger_sequence", Column: "dex_name", Value: utils.DEX_NAME_AQUARIUS},
+	}
+}
+
+func (aquariusProcessor) Filter(tx ingest.LedgerTransaction) bool {
+	if !tx.Successful() {
+		return false
+	}
+	for _, op := range tx.Envelope.Operations() {
+		if invokesContract(op, aquariusContracts) {
+			return !hasReflectorInvocation(tx)
+		}
+	}
+	return false
+}
+
+// invokesContract tells whether op calls one of contracts, either directly
+// or somewhere in the invocation trees it carries authorization for.
+func invokesContract(op xdr.Operation, contracts map[string]bool) bool {
+	invokeOp, ok := op.Body.GetInvokeHostFunctionOp()
+	if !ok {
+		return false
+	}
+	if args, ok := invokeOp.HostFunction.GetInvokeContract(); ok && contracts[contractAddress(args.ContractAddress)] {
+		return true
+	}
+	for _, auth := range invokeOp.Auth {
+		if authorizesContract(auth.RootInvocation, contracts) {
+			return true
+		}
+	}
+	return false
+}
+
+func authorizesContract(invocation xdr.SorobanAuthorizedInvocation, contracts map[string]bool) bool {
+	if args, ok := invocation.Function.GetContractFn(); ok && contracts[contractAddress(args.ContractAddress)] {
+		return true
+	}
+	for _, sub := range invocation.SubInvocations {
+		if authorizesContract(sub, contracts) {
+			return true
+		}
+	}
+	return false
+}
+
+// contractAddress is the strkey of address, empty when it cannot be encoded.
+func contractAddress(address xdr.ScAddress) string {
+	addr, err := address.String()
+	if err != nil {
+		return ""
+	}
+	return addr
+}
+
+func (aquariusProcessor) Process(ctx context.Context, tx ingest.LedgerTransaction, ledgerMeta xdr.LedgerCloseMeta) ([]models.Row, error) {
+	return ProcessAquariusTransaction(ctx, tx, ledgerMeta.LedgerSequence(), ledgerMeta.ClosedAt())
+}
+
+func ProcessAquariusTransaction(ctx context.Context, tx ingest.LedgerTransaction, seq uint32, blocktime time.Time) ([]models.Row, error) {
+	var tx_array []models.Row
+
 	events, err := tx.GetContractEvents()
 	if err != nil {
-		return
+		return nil, err
 	}
-	for _, event := range events {
+
+	for eventIndex, event := range events {
 		body := event.Body.V0
-		if body == nil {
+		if body == nil || len(body.Topics) == 0 || event.ContractId == nil {
 			continue
 		}
+
 		scAddr := xdr.ScAddress{
 			Type:       xdr.ScAddressTypeScAddressTypeContract,
 			ContractId: event.ContractId,
 		}
+
 		event_symbol, _ := body.Topics[0].GetSym()
 		eventname := string(event_symbol)
+
 		switch eventname {
 		case "trade":
+			pool_addr, _ := scAddr.String()
+			if len(body.Topics) < 3 || !aquariusContracts[pool_addr] {
+				continue
+			}
 			token_in_sym, _ := body.Topics[1].GetAddress()
 			token_out_sym, _ := body.Topics[2].GetAddress()
-			pool_addr, _ := scAddr.String()
 			token_in, _ := token_in_sym.String()
 			token_out, _ := token_out_sym.String()
+
 			tx_instance := models.TransactionModels{}
 			tx_instance.BlockTime = blocktime
 			tx_instance.LedgerSequence = seq
 			tx_instance.TransactionHash = tx.Result.TransactionHash.HexString()
+			tx_instance.EventIndex = eventIndex
 			tx_instance.DexName = utils.DEX_NAME_AQUARIUS
 			tx_instance.SourceAccount = tx.Envelope.SourceAccount().GoString()
 			tx_instance.Dex_type = "AMM"
@@ -53,9 +155,11 @@ func ProcessAquariusTransaction(tx ingest.LedgerTransaction, seq uint32, blockti
 			}
 
 			tx_array = append(tx_array, tx_instance)
+			logging.From(ctx).Debug("Aquarius trade", "event_index", eventIndex, "pool", pool_addr)
+			tx_array = append(tx_array, tokenRows(ctx, seq, token_in, token_out)...)
+			tx_array = append(tx_array, poolRows(ctx, seq, pool_addr)...)
 		}
 	}
-	for _, item := range tx_array {
-		utils.PrettyPrintTransaction(item)
-	}
+
+	return tx_array, nil
 }
@@ -0,0 +1,135 @@
+package tx_handlers
+
+import (
+	"context"
+
+	"github.com/celerfi/stellar-indexer-go/logging"
+	"github.com/celerfi/stellar-indexer-go/models"
+	"github.com/stellar/go/ingest"
+	"github.com/stellar/go/xdr"
+)
+
+func init() {
+	Register(failedOperationsProcessor{})
+}
+
+// failedOperationsProcessor records every operation of a failed transaction
+// with its decoded result code: underfunded and cross-self offers, full
+// trustlines, trapped contract invocations and so on.
+type failedOperationsProcessor struct{}
+
+func (failedOperationsProcessor) Name() string { return "failed_operations" }
+
+func (failedOperationsProcessor) Scopes() []models.RowScope {
+	return []models.RowScope{
+		{Table: "failed_operations", LedgerColumn: "ledger_sequence"},
+	}
+}
+
+func (failedOperationsProcessor) Filter(tx ingest.LedgerTransaction) bool {
+	return !tx.Successful()
+}
+
+func (failedOperationsProcessor) Process(ctx context.Context, tx ingest.LedgerTransaction, ledgerMeta xdr.LedgerCloseMeta) ([]models.Row, error) {
+	txCode := transactionResultCode(tx.Result.Result)
+	opResults, _ := tx.Result.OperationResults()
+	fee, _ := tx.FeeCharged()
+
+	var rows []models.Row
+	for opIndex, op := range tx.Envelope.Operations() {
+		failed := models.FailedOperation{
+			BlockTime:             ledgerMeta.ClosedAt(),
+			LedgerSequence:        ledgerMeta.LedgerSequence(),
+			TransactionHash:       tx.Result.TransactionHash.HexString(),
+			OperationIndex:        opIndex,
+			OperationType:         op.Body.Type.String(),
+			SourceAccount:         operationSource(tx, op),
+			TransactionResultCode: txCode,
+			ResultCode:            txCode,
+			FeeCharged:            fee,
+		}
+		if opIndex < len(opResults) {
+			failed.ResultCode = operationResultCode(opResults[opIndex])
+		}
+		if invoke, ok := op.Body.GetInvokeHostFunctionOp(); ok {
+			if call, ok := invoke.HostFunction.GetInvokeContract(); ok {
+				failed.ContractID, _ = call.ContractAddress.String()
+				failed.FunctionName = string(call.FunctionName)
+			}
+		}
+		logging.From(ctx).Debug("Failed operation", logging.KeyOpIndex, opIndex, "result_code", failed.ResultCode)
+		rows = append(rows, failed)
+	}
+	return rows, nil
+}
+
+// operationSource is the operation's source account, which defaults to the
+// transaction's.
+func operationSource(tx ingest.LedgerTransaction, op xdr.Operation) string {
+	if op.SourceAccount != nil {
+		return op.SourceAccount.ToAccountId().Address()
+	}
+	return tx.Envelope.SourceAccount().ToAccountId().Address()
+}
+
+// transactionResultCode names the result of a transaction. For fee bumps it
+// is the inner transaction's result that explains the failure.
+func transactionResultCode(result xdr.TransactionResult) string {
+	if inner, ok := result.Result.GetInnerResultPair(); ok {
+		return inner.Result.Result.Code.String()
+	}
+	return result.Result.Code.String()
+}
+
+// operationResultCode names the result of an applied operation, e.g.
+// ManageSellOfferResultCodeManageSellOfferUnderfunded, for the operations
+// the indexer handles. Others are named by their outer result code.
+func operationResultCode(result xdr.OperationResult) string {
+	if result.Code != xdr.OperationResultCodeOpInner || result.Tr == nil {
+		return result.Code.String()
+	}
+	tr := *result.Tr
+	switch tr.Type {
+	case xdr.OperationTypePayment:
+		if r, ok := tr.GetPaymentResult(); ok {
+			return r.Code.String()
+		}
+	case xdr.OperationTypeManageSellOffer:
+		if r, ok := tr.GetManageSellOfferResult(); ok {
+			return r.Code.String()
+		}
+	case xdr.OperationTypeCreatePassiveSellOffer:
+		if r, ok := tr.GetCreatePassiveSellOfferResult(); ok {
+			return r.Code.String()
+		}
+	case xdr.OperationTypeManageBuyOffer:
+		if r, ok := tr.GetManageBuyOfferResult(); ok {
+			return r.Code.String()
+		}
+	case xdr.OperationTypePathPaymentStrictSend:
+		if r, ok := tr.GetPathPaymentStrictSendResult(); ok {
+			return r.Code.String()
+		}
+	case xdr.OperationTypePathPaymentStrictReceive:
+		if r, ok := tr.GetPathPaymentStrictReceiveResult(); ok {
+			return r.Code.String()
+		}
+	case xdr.OperationTypeChangeTrust:
+		if r, ok := tr.GetChangeTrustResult(); ok {
+			return r.Code.String()
+		}
+	case xdr.OperationTypeLiquidityPoolDeposit:
+		if r, ok := tr.GetLiquidityPoolDepositResult(); ok {
+			return r.Code.String()
+		}
+	case xdr.OperationTypeLiquidityPoolWithdraw:
+		if r, ok := tr.GetLiquidityPoolWithdrawResult(); ok {
+			return r.Code.String()
+		}
+	case xdr.OperationTypeInvokeHostFunction:
+		if r, ok := tr.GetInvokeHostFunctionResult(); ok {
+			return r.Code.String()
+		}
+	}
+	return result.Code.String()
+}
@@ -0,0 +1,72 @@
+package tx_handlers
+
+import (
+	"time"
+
+	"github.com/celerfi/stellar-indexer-go/models"
+	"github.com/stellar/go/ingest"
+	"github.com/stellar/go/xdr"
+)
+
+// NewLedgerRow builds the ledgers row of meta from its header. Transaction
+// totals start at zero and are filled in by CountTransaction.
+func NewLedgerRow(meta xdr.LedgerCloseMeta) models.Ledger {
+	entry := meta.LedgerHeaderHistoryEntry()
+	header := entry.Header
+	return models.Ledger{
+		Sequence:        uint32(header.LedgerSeq),
+		Hash:            entry.Hash.HexString(),
+		PreviousHash:    header.PreviousLedgerHash.HexString(),
+		ClosedAt:        time.Unix(int64(header.ScpValue.CloseTime), 0).UTC(),
+		ProtocolVersion: uint32(header.LedgerVersion),
+		BaseFee:         uint32(header.BaseFee),
+		BaseReserve:     uint32(header.BaseReserve),
+		TotalCoins:      int64(header.TotalCoins),
+		FeePool:         int64(header.FeePool),
+	}
+}
+
+// CountTransaction adds tx to the transaction, operation and fee totals of
+// row. Every transaction of the ledger is counted, failed ones included.
+func CountTransaction(row *models.Ledger, tx ingest.LedgerTransaction) {
+	ops := len(tx.Envelope.Operations())
+	if tx.Successful() {
+		row.SuccessfulTransactionCount++
+		row.SuccessfulOperationCount += ops
+	} else {
+		row.FailedTransactionCount++
+		row.FailedOperationCount += ops
+	}
+	if fee, ok := tx.FeeCharged(); ok {
+		row.FeeCharged += fee
+	}
+	if fees, ok := sorobanFees(tx.UnsafeMeta); ok {
+		row.SorobanNonRefundableFeeCharged += int64(fees.TotalNonRefundableResourceFeeCharged)
+		row.SorobanRefundableFeeCharged += int64(fees.TotalRefundableResourceFeeCharged)
+		row.SorobanRentFeeCharged += int64(fees.RentFeeCharged)
+	}
+}
+
+// sorobanFees returns the resource fees charged to a Soroban transaction.
+// Classic transactions carry no Soroban meta and report false.
+func sorobanFees(meta xdr.TransactionMeta) (xdr.SorobanTransactionMetaExtV1, bool) {
+	var ext xdr.SorobanTransactionMetaExt
+	switch meta.V {
+	case 3:
+		if meta.V3.SorobanMeta == nil {
+			return xdr.SorobanTransactionMetaExtV1{}, false
+		}
+		ext = meta.V3.SorobanMeta.Ext
+	case 4:
+		if meta.V4.SorobanMeta == nil {
+			return xdr.SorobanTransactionMetaExtV1{}, false
+		}
+		ext = meta.V4.SorobanMeta.Ext
+	default:
+		return xdr.SorobanTransactionMetaExtV1{}, false
+	}
+	if ext.V1 == nil {
+		return xdr.SorobanTransactionMetaExtV1{}, false
+	}
+	return *ext.V1, true
+}
@@ -0,0 +1,155 @@
+package tx_handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/celerfi/stellar-indexer-go/logging"
+	"github.com/celerfi/stellar-indexer-go/models"
+	"github.com/celerfi/stellar-indexer-go/utils"
+	"github.com/stellar/go/ingest"
+	"github.com/stellar/go/xdr"
+)
+
+func init() {
+	Register(liquidityPoolProcessor{})
+}
+
+// liquidityPoolProcessor indexes deposits into and withdrawals from classic
+// liquidity pools and registers every classic pool it sees, from pool share
+// trustlines, deposits, withdrawals and swaps. The swaps themselves are
+// trades of the operations that crossed the pool.
+type liquidityPoolProcessor struct{}
+
+func (liquidityPoolProcessor) Name() string { return "liquidity_pools" }
+
+func (liquidityPoolProcessor) Scopes() []models.RowScope {
+	return []models.RowScope{
+		{Table: "liquidity_pool_operations", LedgerColumn: "ledger_sequence"},
+	}
+}
+
+func (liquidityPoolProcessor) Filter(tx ingest.LedgerTransaction) bool {
+	if !tx.Successful() {
+		return false
+	}
+	for opIndex, op := range tx.Envelope.Operations() {
+		if touchesLiquidityPool(tx, op, opIndex) {
+			return true
+		}
+	}
+	return false
+}
+
+func (liquidityPoolProcessor) Process(ctx context.Context, tx ingest.LedgerTransaction, ledgerMeta xdr.LedgerCloseMeta) ([]models.Row, error) {
+	seq := ledgerMeta.LedgerSequence()
+	blockTime := ledgerMeta.ClosedAt()
+
+	var rows []models.Row
+	for opIndex, op := range tx.Envelope.Operations() {
+		if !touchesLiquidityPool(tx, op, opIndex) {
+			continue
+		}
+		changes, err := tx.GetOperationChanges(uint32(opIndex))
+		if err != nil {
+			return rows, fmt.Errorf("failed to read changes of operation %d: %w", opIndex, err)
+		}
+
+		opCtx := logging.With(ctx, logging.KeyOpIndex, opIndex)
+		for _, change := range changes {
+			if change.Type != xdr.LedgerEntryTypeLiquidityPool {
+				continue
+			}
+			entry := change.Post
+			if entry == nil {
+				entry = change.Pre
+			}
+			rows = append(rows, classicPoolRows(opCtx, seq, entry.Data.MustLiquidityPool(), blockTime)...)
+		}
+
+		switch op.Body.Type {
+		case xdr.OperationTypeLiquidityPoolDeposit:
+			poolID := op.Body.MustLiquidityPoolDepositOp().LiquidityPoolId
+			rows = append(rows, poolOperationRows(opCtx, tx, op, seq, opIndex, poolID, changes, blockTime)...)
+		case xdr.OperationTypeLiquidityPoolWithdraw:
+			poolID := op.Body.MustLiquidityPoolWithdrawOp().LiquidityPoolId
+			rows = append(rows, poolOperationRows(opCtx, tx, op, seq, opIndex, poolID, changes, blockTime)...)
+		}
+	}
+	return rows, nil
+}
+
+// touchesLiquidityPool tells whether the operation at opIndex can create or
+// change a classic pool: a deposit, a withdrawal, a pool share trustline
+// change or an offer or path payment that swapped against a pool.
+func touchesLiquidityPool(tx ingest.LedgerTransaction, op xdr.Operation, opIndex int) bool {
+	switch op.Body.Type {
+	case xdr.OperationTypeLiquidityPoolDeposit, xdr.OperationTypeLiquidityPoolWithdraw:
+		return true
+	case xdr.OperationTypeChangeTrust:
+		return op.Body.MustChangeTrustOp().Line.Type == xdr.AssetTypeAssetTypePoolShare
+	}
+	for _, claim := range operationClaims(tx.Result.Result.Result.Results, opIndex) {
+		if claim.Type == xdr.ClaimAtomTypeClaimAtomTypeLiquidityPool {
+			return true
+		}
+	}
+	return false
+}
+
+// poolOperationRows reads what a deposit or withdrawal moved from the
+// difference between the pool entry before and after the operation.
+func poolOperationRows(
+	ctx context.Context,
+	tx ingest.LedgerTransaction,
+	op xdr.Operation,
+	seq uint32,
+	opIndex int,
+	poolID xdr.PoolId,
+	changes []ingest.Change,
+	blockTime time.Time,
+) []models.Row {
+	var pre, post *xdr.LiquidityPoolEntryConstantProduct
+	for _, change := range changes {
+		if change.Type != xdr.LedgerEntryTypeLiquidityPool || change.Pre == nil || change.Post == nil {
+			continue
+		}
+		if entry := change.Post.Data.MustLiquidityPool(); entry.LiquidityPoolId == poolID {
+			pre = change.Pre.Data.MustLiquidityPool().Body.ConstantProduct
+			post = entry.Body.ConstantProduct
+		}
+	}
+	if pre == nil || post == nil {
+		logging.From(ctx).Warn("Liquidity pool operation without a pool change", "pool", liquidityPoolID(poolID))
+		return nil
+	}
+
+	row := models.LiquidityPoolOperation{
+		BlockTime:       blockTime,
+		LedgerSequence:  seq,
+		TransactionHash: tx.Result.TransactionHash.HexString(),
+		OperationIndex:  opIndex,
+		OperationType:   op.Body.Type.String(),
+		LiquidityPoolID: liquidityPoolID(poolID),
+		Account:         operationSource(tx, op),
+		AssetA:          utils.FormatAsset(post.Params.AssetA),
+		AssetB:          utils.FormatAsset(post.Params.AssetB),
+		ReserveA:        float64(post.ReserveA) / 1e7,
+		ReserveB:        float64(post.ReserveB) / 1e7,
+		TotalShares:     float64(post.TotalPoolShares) / 1e7,
+	}
+	// A deposit grows the reserves and mints shares, a withdrawal shrinks
+	// both; the row holds the amounts that moved either way.
+	amountA, amountB := post.ReserveA-pre.ReserveA, post.ReserveB-pre.ReserveB
+	shares := post.TotalPoolShares - pre.TotalPoolShares
+	if op.Body.Type == xdr.OperationTypeLiquidityPoolWithdraw {
+		amountA, amountB, shares = -amountA, -amountB, -shares
+	}
+	row.AmountA = float64(amountA) / 1e7
+	row.AmountB = float64(amountB) / 1e7
+	row.Shares = float64(shares) / 1e7
+
+	logging.From(ctx).Debug("Liquidity pool operation", "pool", row.LiquidityPoolID, "type", row.OperationType)
+	return []models.Row{row}
+}
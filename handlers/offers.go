@@ -0,0 +1,143 @@
+package tx_handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/celerfi/stellar-indexer-go/models"
+	"github.com/celerfi/stellar-indexer-go/utils"
+	"github.com/stellar/go/ingest"
+	"github.com/stellar/go/xdr"
+)
+
+func init() {
+	Register(offersProcessor{})
+}
+
+// offersProcessor keeps the offers table: the latest state of every offer,
+// from the offer ledger entries each operation created, updated or removed.
+type offersProcessor struct{}
+
+func (offersProcessor) Name() string { return "offers" }
+
+// The offers table holds one row per offer rather than rows per ledger, so
+// reprocessing deletes nothing: replayed changes go through the upsert,
+// which never replaces a newer row and keeps created_ledger.
+func (offersProcessor) Scopes() []models.RowScope {
+	return nil
+}
+
+// offerOperations are the operations that can create, change or remove an
+// offer entry.
+var offerOperations = map[xdr.OperationType]bool{
+	xdr.OperationTypeManageSellOffer:          true,
+	xdr.OperationTypeManageBuyOffer:           true,
+	xdr.OperationTypeCreatePassiveSellOffer:   true,
+	xdr.OperationTypePathPaymentStrictSend:    true,
+	xdr.OperationTypePathPaymentStrictReceive: true,
+	xdr.OperationTypeAllowTrust:               true,
+	xdr.OperationTypeSetTrustLineFlags:        true,
+	xdr.OperationTypeRevokeSponsorship:        true,
+}
+
+func (offersProcessor) Filter(tx ingest.LedgerTransaction) bool {
+	if !tx.Successful() {
+		return false
+	}
+	for _, op := range tx.Envelope.Operations() {
+		if offerOperations[op.Body.Type] {
+			return true
+		}
+	}
+	return false
+}
+
+func (offersProcessor) Process(ctx context.Context, tx ingest.LedgerTransaction, ledgerMeta xdr.LedgerCloseMeta) ([]models.Row, error) {
+	seq := ledgerMeta.LedgerSequence()
+	blockTime := ledgerMeta.ClosedAt()
+
+	var rows []models.Row
+	for opIndex, op := range tx.Envelope.Operations() {
+		if !offerOperations[op.Body.Type] {
+			continue
+		}
+		changes, err := tx.GetOperationChanges(uint32(opIndex))
+		if err != nil {
+			return rows, fmt.Errorf("failed to read changes of operation %d: %w", opIndex, err)
+		}
+		for _, change := range changes {
+			if change.Type != xdr.LedgerEntryTypeOffer {
+				continue
+			}
+			rows = append(rows, offerRow(tx, op, opIndex, change, seq, blockTime))
+		}
+	}
+	return rows, nil
+}
+
+// offerRow is the state of the offer change left behind.
+func offerRow(tx ingest.LedgerTransaction, op xdr.Operation, opIndex int, change ingest.Change, seq uint32, blockTime time.Time) models.Offer {
+	row := models.Offer{
+		LastModifiedLedger:  seq,
+		LastTransactionHash: tx.Result.TransactionHash.HexString(),
+		UpdatedAt:           blockTime,
+	}
+
+	var entry xdr.OfferEntry
+	switch change.ChangeType {
+	case xdr.LedgerEntryChangeTypeLedgerEntryCreated:
+		entry = change.Post.Data.MustOffer()
+		row.CreatedLedger = seq
+		row.State = utils.OFFER_STATE_OPEN
+	case xdr.LedgerEntryChangeTypeLedgerEntryRemoved:
+		entry = change.Pre.Data.MustOffer()
+		row.State = offerRemovalState(tx, op, opIndex, entry.OfferId)
+	default:
+		entry = change.Post.Data.MustOffer()
+		row.State = utils.OFFER_STATE_OPEN
+	}
+
+	row.OfferID = uint64(entry.OfferId)
+	row.SellerAccount = entry.SellerId.Address()
+	row.SellingAsset = utils.FormatAsset(entry.Selling)
+	row.BuyingAsset = utils.FormatAsset(entry.Buying)
+	row.PriceN = int32(entry.Price.N)
+	row.PriceD = int32(entry.Price.D)
+	row.Price = float64(entry.Price.N) / float64(entry.Price.D)
+	row.Flags = uint32(entry.Flags)
+	if row.State == utils.OFFER_STATE_OPEN {
+		row.Amount = float64(entry.Amount) / 1e7
+	}
+	return row
+}
+
+// offerRemovalState tells why the operation at opIndex removed offerID: its
+// owner deleted it with amount 0, it was filled to the end, either by
+// another operation crossing it or by the owner's own update crossing the
+// book, or it was taken off the book for another reason.
+func offerRemovalState(tx ingest.LedgerTransaction, op xdr.Operation, opIndex int, offerID xdr.Int64) string {
+	switch op.Body.Type {
+	case xdr.OperationTypeManageSellOffer:
+		if manage := op.Body.MustManageSellOfferOp(); manage.OfferId == offerID {
+			if manage.Amount == 0 {
+				return utils.OFFER_STATE_CANCELLED
+			}
+			return utils.OFFER_STATE_CONSUMED
+		}
+	case xdr.OperationTypeManageBuyOffer:
+		if manage := op.Body.MustManageBuyOfferOp(); manage.OfferId == offerID {
+			if manage.BuyAmount == 0 {
+				return utils.OFFER_STATE_CANCELLED
+			}
+			return utils.OFFER_STATE_CONSUMED
+		}
+	}
+
+	for _, claim := range operationClaims(tx.Result.Result.Result.Results, opIndex) {
+		if claim.Type != xdr.ClaimAtomTypeClaimAtomTypeLiquidityPool && claim.OfferId() == offerID {
+			return utils.OFFER_STATE_CONSUMED
+		}
+	}
+	return utils.OFFER_STATE_REMOVED
+}
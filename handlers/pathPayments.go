@@ -0,0 +1,149 @@
+package tx_handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/celerfi/stellar-indexer-go/logging"
+	"github.com/celerfi/stellar-indexer-go/models"
+	"github.com/celerfi/stellar-indexer-go/utils"
+	"github.com/stellar/go/ingest"
+	"github.com/stellar/go/xdr"
+)
+
+func init() {
+	Register(pathPaymentProcessor{})
+}
+
+// pathPaymentProcessor indexes strict send and strict receive path payments:
+// the payment end to end and a trade for every offer or pool it crossed.
+type pathPaymentProcessor struct{}
+
+func (pathPaymentProcessor) Name() string { return "path_payments" }
+
+func (pathPaymentProcessor) Scopes() []models.RowScope {
+	return []models.RowScope{
+		{Table: "path_payments", LedgerColumn: "ledger_sequence"},
+		{Table: "trades", LedgerColumn: "ledger_sequence", Column: "operation_type", Value: xdr.OperationTypePathPaymentStrictSend.String()},
+		{Table: "trades", LedgerColumn: "ledger_sequence", Column: "operation_type", Value: xdr.OperationTypePathPaymentStrictReceive.String()},
+	}
+}
+
+func (pathPaymentProcessor) Filter(tx ingest.LedgerTransaction) bool {
+	if !tx.Successful() {
+		return false
+	}
+	for _, op := range tx.Envelope.Operations() {
+		switch op.Body.Type {
+		case xdr.OperationTypePathPaymentStrictSend, xdr.OperationTypePathPaymentStrictReceive:
+			return true
+		}
+	}
+	return false
+}
+
+func (pathPaymentProcessor) Process(ctx context.Context, tx ingest.LedgerTransaction, ledgerMeta xdr.LedgerCloseMeta) ([]models.Row, error) {
+	seq := ledgerMeta.LedgerSequence()
+	blockTime := ledgerMeta.ClosedAt()
+	opResults := tx.Result.Result.Result.Results
+
+	var rows []models.Row
+	for opIndex, op := range tx.Envelope.Operations() {
+		opCtx := logging.With(ctx, logging.KeyOpIndex, opIndex)
+		switch op.Body.Type {
+		case xdr.OperationTypePathPaymentStrictSend:
+			rows = append(rows, HandlePathPaymentStrictSend(opCtx, tx, op, seq, opIndex, opResults, blockTime)...)
+		case xdr.OperationTypePathPaymentStrictReceive:
+			rows = append(rows, HandlePathPaymentStrictReceive(opCtx, tx, op, seq, opIndex, opResults, blockTime)...)
+		}
+	}
+	return rows, nil
+}
+
+func HandlePathPaymentStrictSend(
+	ctx context.Context,
+	tx ingest.LedgerTransaction,
+	op xdr.Operation,
+	seq uint32,
+	opIndex int,
+	results *[]xdr.OperationResult,
+	blockTime time.Time,
+) []models.Row {
+	payment := op.Body.MustPathPaymentStrictSendOp()
+	if results == nil || opIndex >= len(*results) {
+		return nil
+	}
+
+	result := (*results)[opIndex].Tr.PathPaymentStrictSendResult
+	if result == nil || result.Code != xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendSuccess {
+		return nil
+	}
+
+	row := pathPaymentRow(tx, op, seq, opIndex, blockTime, payment.SendAsset, payment.DestAsset, payment.Path, payment.Destination)
+	row.SourceAmount = float64(payment.SendAmount) / 1e7
+	row.DestinationAmount = float64(result.DestAmount()) / 1e7
+
+	claims := result.MustSuccess().Offers
+	logging.From(ctx).Debug("Path payment strict send", "claims", len(claims))
+	rows := []models.Row{row}
+	return append(rows, claimTrades(tx, op, seq, opIndex, claims, blockTime)...)
+}
+
+func HandlePathPaymentStrictReceive(
+	ctx context.Context,
+	tx ingest.LedgerTransaction,
+	op xdr.Operation,
+	seq uint32,
+	opIndex int,
+	results *[]xdr.OperationResult,
+	blockTime time.Time,
+) []models.Row {
+	payment := op.Body.MustPathPaymentStrictReceiveOp()
+	if results == nil || opIndex >= len(*results) {
+		return nil
+	}
+
+	result := (*results)[opIndex].Tr.PathPaymentStrictReceiveResult
+	if result == nil || result.Code != xdr.PathPaymentStrictReceiveResultCodePathPaymentStrictReceiveSuccess {
+		return nil
+	}
+
+	row := pathPaymentRow(tx, op, seq, opIndex, blockTime, payment.SendAsset, payment.DestAsset, payment.Path, payment.Destination)
+	row.SourceAmount = float64(result.SendAmount()) / 1e7
+	row.DestinationAmount = float64(payment.DestAmount) / 1e7
+
+	claims := result.MustSuccess().Offers
+	logging.From(ctx).Debug("Path payment strict receive", "claims", len(claims))
+	rows := []models.Row{row}
+	return append(rows, claimTrades(tx, op, seq, opIndex, claims, blockTime)...)
+}
+
+// pathPaymentRow fills everything of a path payment but the amounts, which
+// strict send and strict receive take from different places.
+func pathPaymentRow(
+	tx ingest.LedgerTransaction,
+	op xdr.Operation,
+	seq uint32,
+	opIndex int,
+	blockTime time.Time,
+	sendAsset, destAsset xdr.Asset,
+	path []xdr.Asset,
+	destination xdr.MuxedAccount,
+) models.PathPayment {
+	formattedPath := make([]string, 0, len(path))
+	for _, asset := range path {
+		formattedPath = append(formattedPath, utils.FormatAsset(asset))
+	}
+	return models.PathPayment{
+		BlockTime:          blockTime,
+		LedgerSequence:     seq,
+		TransactionHash:    tx.Result.TransactionHash.HexString(),
+		OperationIndex:     opIndex,
+		OperationType:      op.Body.Type.String(),
+		SourceAccount:      operationSource(tx, op),
+		DestinationAccount: destination.ToAccountId().Address(),
+		SourceAsset:        utils.FormatAsset(sendAsset),
+		DestinationAsset:   utils.FormatAsset(destAsset),
+		Path:               formattedPath,
+	}
+}
@@ -1,16 +1,20 @@
 package tx_handlers
 
 import (
-	"fmt"
+	"context"
 	"time"
 
+	"github.com/celerfi/stellar-indexer-go/logging"
 	"github.com/celerfi/stellar-indexer-go/models"
 	"github.com/celerfi/stellar-indexer-go/utils"
+	"github.com/stellar/go/xdr"
 )
 
-func AddPoolDetails(poolAddress string) {
-	if utils.PoolExistsInDb(poolAddress) {
-		return
+// poolRows returns the liquidity_pools row of poolAddress the first time this
+// process sees the pool, claiming it for ledger seq. See seenRows.
+func poolRows(ctx context.Context, seq uint32, poolAddress string) []models.Row {
+	if !seenPools.claim(poolAddress, seq) {
+		return nil
 	}
 
 	// todo - fetching actual pool details {tokens,fee & type}
@@ -23,6 +27,29 @@ func AddPoolDetails(poolAddress string) {
 		CreatedAt:   time.Now().UTC(),
 	}
 
-	utils.SavePoolToDB(pool)
-	fmt.Printf("Saved new placeholder pool: %s\n", poolAddress)
-}
\ No newline at end of file
+	logging.From(ctx).Info("Found new placeholder pool", "pool", poolAddress)
+	return []models.Row{pool}
+}
+
+// classicPoolRows returns the liquidity_pools row of a classic pool the first
+// time this process sees it. Unlike contract pools, a classic pool's ledger
+// entry carries its assets and fee.
+func classicPoolRows(ctx context.Context, seq uint32, entry xdr.LiquidityPoolEntry, blockTime time.Time) []models.Row {
+	poolAddress := liquidityPoolID(entry.LiquidityPoolId)
+	if !seenPools.claim(poolAddress, seq) {
+		return nil
+	}
+
+	params := entry.Body.MustConstantProduct().Params
+	pool := models.LiquidityPool{
+		PoolAddress: poolAddress,
+		TokenA:      utils.FormatAsset(params.AssetA),
+		TokenB:      utils.FormatAsset(params.AssetB),
+		FeeBps:      int32(params.Fee),
+		Type:        "CONSTANT_PRODUCT",
+		CreatedAt:   blockTime,
+	}
+
+	logging.From(ctx).Info("Found new classic pool", "pool", poolAddress)
+	return []models.Row{pool}
+}
@@ -0,0 +1,152 @@
+package tx_handlers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/celerfi/stellar-indexer-go/metrics"
+	"github.com/celerfi/stellar-indexer-go/models"
+	"github.com/stellar/go/ingest"
+	"github.com/stellar/go/xdr"
+)
+
+// Processor derives rows from the transactions it is interested in. Filter
+// sees failed transactions too and must be cheap; Process is run on the
+// worker pool for every match. Scopes
+// names the stored rows the processor owns so they can be rebuilt.
+type Processor interface {
+	Name() string
+	Filter(tx ingest.LedgerTransaction) bool
+	Process(ctx context.Context, tx ingest.LedgerTransaction, ledgerMeta xdr.LedgerCloseMeta) ([]models.Row, error)
+	Scopes() []models.RowScope
+}
+
+// ProcessorStats counts what a processor has done since startup.
+type ProcessorStats struct {
+	Transactions uint64
+	Rows         uint64
+	Errors       uint64
+	Duration     time.Duration
+}
+
+type processorCounters struct {
+	transactions atomic.Uint64
+	rows         atomic.Uint64
+	errors       atomic.Uint64
+	nanos        atomic.Int64
+}
+
+var (
+	availableLock sync.RWMutex
+	available     = map[string]Processor{}
+)
+
+// Register makes a processor available for selection by name. Processors
+// register themselves from init.
+func Register(p Processor) {
+	availableLock.Lock()
+	defer availableLock.Unlock()
+	if _, dup := available[p.Name()]; dup {
+		panic("processor registered twice: " + p.Name())
+	}
+	available[p.Name()] = p
+}
+
+// ProcessorNames lists every registered processor.
+func ProcessorNames() []string {
+	availableLock.RLock()
+	defer availableLock.RUnlock()
+	names := make([]string, 0, len(available))
+	for name := range available {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Registry is the set of processors enabled for this deployment.
+type Registry struct {
+	processors []Processor
+	counters   map[string]*processorCounters
+}
+
+// NewRegistry enables the named processors, or every registered processor
+// when names is empty.
+func NewRegistry(names []string) (*Registry, error) {
+	if len(names) == 0 {
+		names = ProcessorNames()
+	}
+
+	availableLock.RLock()
+	defer availableLock.RUnlock()
+
+	r := &Registry{counters: map[string]*processorCounters{}}
+	for _, name := range names {
+		p, ok := available[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown processor %q", name)
+		}
+		if _, dup := r.counters[name]; dup {
+			continue
+		}
+		r.processors = append(r.processors, p)
+		r.counters[name] = &processorCounters{}
+	}
+	return r, nil
+}
+
+// Processors returns the enabled processors in the order they were enabled.
+func (r *Registry) Processors() []Processor {
+	return r.processors
+}
+
+// Process runs p on tx and records its stats. A panic inside the processor is
+// reported as an error of that processor.
+func (r *Registry) Process(ctx context.Context, p Processor, tx ingest.LedgerTransaction, ledgerMeta xdr.LedgerCloseMeta) (rows []models.Row, err error) {
+	counters := r.counters[p.Name()]
+	start := time.Now()
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("processor %s panicked: %v", p.Name(), rec)
+		}
+		elapsed := time.Since(start)
+		counters.transactions.Add(1)
+		counters.rows.Add(uint64(len(rows)))
+		counters.nanos.Add(int64(elapsed))
+		metrics.ProcessorTransactions.WithLabelValues(p.Name()).Inc()
+		metrics.ProcessorRows.WithLabelValues(p.Name()).Add(float64(len(rows)))
+		metrics.ProcessorDuration.WithLabelValues(p.Name()).Observe(elapsed.Seconds())
+		if err != nil {
+			counters.errors.Add(1)
+			metrics.ProcessorErrors.WithLabelValues(p.Name()).Inc()
+			err = fmt.Errorf("processor %s on tx %s: %w", p.Name(), tx.Result.TransactionHash.HexString(), err)
+		}
+	}()
+	return p.Process(ctx, tx, ledgerMeta)
+}
+
+// Stats returns a snapshot of every enabled processor's counters.
+func (r *Registry) Stats() map[string]ProcessorStats {
+	stats := make(map[string]ProcessorStats, len(r.counters))
+	for name, c := range r.counters {
+		stats[name] = ProcessorStats{
+			Transactions: c.transactions.Load(),
+			Rows:         c.rows.Load(),
+			Errors:       c.errors.Load(),
+			Duration:     time.Duration(c.nanos.Load()),
+		}
+	}
+	return stats
+}
+
+func priceTickRows(ticks []models.PriceTick) []models.Row {
+	rows := make([]models.Row, 0, len(ticks))
+	for _, t := range ticks {
+		rows = append(rows, t)
+	}
+	return rows
+}
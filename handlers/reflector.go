@@ -1,21 +1,19 @@
 package tx_handlers
 
 import (
-	"fmt"
-	"log"
+	"context"
+	"log/slog"
 	"time"
 
+	"github.com/celerfi/stellar-indexer-go/logging"
 	"github.com/celerfi/stellar-indexer-go/models"
 	"github.com/celerfi/stellar-indexer-go/utils"
 	"github.com/stellar/go/ingest"
 	"github.com/stellar/go/xdr"
 )
 
-var reflectorContracts = map[string]bool{
-	"CAFJZQWSED6YAWZU3GWRTOCNPPCGBN32L7QV43XX5LZLFTK6JLN34DLN": true,
-	"CALI2BYU2JE6WVRUFYTS6MSBNEHGJ35P4AVCZYF3B6QOE3QKOB2PLE6M": true,
-	"CBKGPWGKSKZF52CFHMTRR23TBWTPMRDIYZ4O2P5VS65BMHYH4DXMCJZC": true,
-}
+// reflectorContracts holds the Reflector oracles of the active network profile.
+var reflectorContracts = map[string]bool{}
 
 // contractAssets maps contract address - ordered asset list fetched from the contract.
 var contractAssets = map[string][]string{}
@@ -26,18 +24,77 @@ const (
 	setPriceFuncName  = "set_price"
 )
 
-// InitReflectorAssets calls assets() on each Reflector contract
-// the index - assetID mapping. Call this once before starting the ledger stream.
-func InitReflectorAssets() {
+// InitReflectorAssets tracks the given Reflector contracts and calls assets()
+// on each to get the index - assetID mapping. Call this once before starting
+// the ledger stream.
+func InitReflectorAssets(oracles []string) {
+	reflectorContracts = toSet(oracles)
 	for contractAddr := range reflectorContracts {
 		assets, err := utils.GetReflectorAssets(contractAddr)
 		if err != nil {
-			log.Printf("failed to fetch assets for reflector contract %s: %v", contractAddr, err)
+			slog.Error("Failed to fetch assets for reflector contract", "contract", contractAddr, logging.Err(err))
 			continue
 		}
 		contractAssets[contractAddr] = assets
 	}
-	log.Println("Successfully fetched reflector assets")
+	slog.Info("Fetched reflector assets", "loaded", len(contractAssets), "expected", len(reflectorContracts))
+}
+
+// SetReflectorAssets tracks Reflector contracts whose asset lists are
+// already known, e.g. when replaying captured ledgers offline. It replaces
+// whatever InitReflectorAssets set up.
+func SetReflectorAssets(assets map[string][]string) {
+	reflectorContracts = map[string]bool{}
+	contractAssets = map[string][]string{}
+	for contractAddr, list := range assets {
+		reflectorContracts[contractAddr] = true
+		contractAssets[contractAddr] = list
+	}
+}
+
+func init() {
+	Register(reflectorProcessor{})
+}
+
+// reflectorProcessor turns Reflector set_price invocations into price ticks.
+type reflectorProcessor struct{}
+
+func (reflectorProcessor) Name() string { return "reflector" }
+
+func (reflectorProcessor) Scopes() []models.RowScope {
+	return []models.RowScope{
+		{Table: "price_ticks", LedgerColumn: "ledger_seq", Column: "source_id", Value: reflectorSourceID},
+	}
+}
+
+func (reflectorProcessor) Filter(tx ingest.LedgerTransaction) bool {
+	return tx.Successful() && hasReflectorInvocation(tx)
+}
+
+func (reflectorProcessor) Process(ctx context.Context, tx ingest.LedgerTransaction, ledgerMeta xdr.LedgerCloseMeta) ([]models.Row, error) {
+	var ticks []models.PriceTick
+	for opIndex, op := range tx.Envelope.Operations() {
+		if _, ok := IsReflectorInvocation(op); ok {
+			opCtx := logging.With(ctx, logging.KeyOpIndex, opIndex)
+			ticks = append(ticks, HandleReflectorSetPrice(opCtx, tx, op, ledgerMeta.LedgerSequence(), ledgerMeta.ClosedAt())...)
+		}
+	}
+	return priceTickRows(ticks), nil
+}
+
+func hasReflectorInvocation(tx ingest.LedgerTransaction) bool {
+	for _, op := range tx.Envelope.Operations() {
+		if _, ok := IsReflectorInvocation(op); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ReflectorAssetsLoaded reports how many of the configured Reflector contracts
+// had their asset list loaded by InitReflectorAssets.
+func ReflectorAssetsLoaded() (loaded, expected int) {
+	return len(contractAssets), len(reflectorContracts)
 }
 
 func IsReflectorInvocation(op xdr.Operation) (string, bool) {
@@ -68,16 +125,16 @@ func IsReflectorInvocation(op xdr.Operation) (string, bool) {
 	return addr, true
 }
 
-func HandleReflectorSetPrice(tx ingest.LedgerTransaction, op xdr.Operation, seq uint32, blocktime time.Time) {
+func HandleReflectorSetPrice(ctx context.Context, tx ingest.LedgerTransaction, op xdr.Operation, seq uint32, blocktime time.Time) []models.PriceTick {
 	invokeOp, ok := op.Body.GetInvokeHostFunctionOp()
 	if !ok {
-		return
+		return nil
 	}
 
 	args := invokeOp.HostFunction.MustInvokeContract()
 
 	if len(args.Args) < 2 {
-		return
+		return nil
 	}
 
 	// Identify which contract this is so we use the right asset list
@@ -87,24 +144,24 @@ func HandleReflectorSetPrice(tx ingest.LedgerTransaction, op xdr.Operation, seq
 	}
 	contractAddr, err := contractAddress.String()
 	if err != nil {
-		return
+		return nil
 	}
 
 	assets, ok := contractAssets[contractAddr]
 	if !ok || len(assets) == 0 {
-		log.Printf("no asset list for reflector contract %s", contractAddr)
-		return
+		logging.From(ctx).Warn("No asset list for reflector contract", "contract", contractAddr)
+		return nil
 	}
 
 	tsVal, ok := args.Args[1].GetU64()
 	if !ok {
-		return
+		return nil
 	}
 	priceTime := time.UnixMilli(int64(tsVal)).UTC()
 
 	updatesVec, ok := args.Args[0].GetVec()
 	if !ok || updatesVec == nil {
-		return
+		return nil
 	}
 
 	var ticks []models.PriceTick
@@ -136,8 +193,14 @@ func HandleReflectorSetPrice(tx ingest.LedgerTransaction, op xdr.Operation, seq
 		})
 	}
 
-	if len(ticks) > 0 {
-		fmt.Printf("TICKS: %+v\n", ticks)
-		utils.InsertPriceTicks(ticks)
+	logging.From(ctx).Debug("Reflector set_price", "contract", contractAddr, "ticks", len(ticks))
+	return ticks
+}
+
+func toSet(items []string) map[string]bool {
+	set := make(map[string]bool, len(items))
+	for _, item := range items {
+		set[item] = true
 	}
+	return set
 }
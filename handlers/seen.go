@@ -0,0 +1,95 @@
+package tx_handlers
+
+import "sync"
+
+// seenRows tracks the token and pool rows this process already emitted, so
+// each is derived once. The first ledger to emit a row claims its key, and
+// the claim only holds once that ledger's rows are committed: a ledger that
+// is dead-lettered or fails to commit releases its claims, so a later ledger
+// emits the row again. Sinks upsert these rows, so emitting one twice only
+// refreshes it.
+type seenRows struct {
+	lock    sync.Mutex
+	claims  map[string]seenClaim
+	pending map[uint32][]string // keys each unsettled ledger claimed
+}
+
+type seenClaim struct {
+	ledger    uint32
+	committed bool
+}
+
+// claim reports whether key is new, claiming it for ledger if so.
+func (s *seenRows) claim(key string, ledger uint32) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.claims[key]; ok {
+		return false
+	}
+	if s.claims == nil {
+		s.claims = map[string]seenClaim{}
+		s.pending = map[uint32][]string{}
+	}
+	s.claims[key] = seenClaim{ledger: ledger}
+	s.pending[ledger] = append(s.pending[ledger], key)
+	return true
+}
+
+// preload marks keys as seen for good, e.g. rows stored by an earlier run.
+func (s *seenRows) preload(keys []string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.claims == nil {
+		s.claims = map[string]seenClaim{}
+		s.pending = map[uint32][]string{}
+	}
+	for _, key := range keys {
+		s.claims[key] = seenClaim{committed: true}
+	}
+}
+
+// release drops the claim ledger holds on key, e.g. when its lookup failed.
+func (s *seenRows) release(key string, ledger uint32) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.claims[key] == (seenClaim{ledger: ledger}) {
+		delete(s.claims, key)
+	}
+}
+
+// settle keeps the claims of ledger when committed and drops them otherwise.
+// It only visits the keys ledger claimed.
+func (s *seenRows) settle(ledger uint32, committed bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, key := range s.pending[ledger] {
+		if s.claims[key] != (seenClaim{ledger: ledger}) {
+			continue
+		}
+		if committed {
+			s.claims[key] = seenClaim{ledger: ledger, committed: true}
+		} else {
+			delete(s.claims, key)
+		}
+	}
+	delete(s.pending, ledger)
+}
+
+var (
+	seenTokens seenRows
+	seenPools  seenRows
+)
+
+// PreloadTokens marks tokens whose details are already stored as seen, so
+// no transaction looks them up again.
+func PreloadTokens(tokens []string) {
+	seenTokens.preload(tokens)
+}
+
+// SettleLedger ends the claims ledger seq holds on token and pool rows,
+// keeping them when its rows were committed. Call it once for every ledger
+// that went through the processors, whatever became of its rows.
+func SettleLedger(seq uint32, committed bool) {
+	seenTokens.settle(seq, committed)
+	seenPools.settle(seq, committed)
+}
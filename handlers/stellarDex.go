@@ -1,31 +1,86 @@
 package tx_handlers
 
 import (
+	"context"
 	"strings"
 	"time"
 
+	"github.com/celerfi/stellar-indexer-go/logging"
 	"github.com/celerfi/stellar-indexer-go/models"
 	"github.com/celerfi/stellar-indexer-go/utils"
 	"github.com/stellar/go/ingest"
 	"github.com/stellar/go/xdr"
 )
 
+func init() {
+	Register(sdexProcessor{})
+}
+
+// sdexProcessor indexes manage buy, manage sell and passive sell offers on the
+// classic order book.
+type sdexProcessor struct{}
+
+func (sdexProcessor) Name() string { return "sdex" }
+
+func (sdexProcessor) Scopes() []models.RowScope {
+	return []models.RowScope{
+		{Table: "transaction_models", LedgerColumn: "ledger_sequence", Column: "dex_name", Value: utils.DEX_NAME_STELLAR_DEX},
+		{Table: "trades", LedgerColumn: "ledger_sequence", Column: "operation_type", Value: xdr.OperationTypeManageBuyOffer.String()},
+		{Table: "trades", LedgerColumn: "ledger_sequence", Column: "operation_type", Value: xdr.OperationTypeManageSellOffer.String()},
+		{Table: "trades", LedgerColumn: "ledger_sequence", Column: "operation_type", Value: xdr.OperationTypeCreatePassiveSellOffer.String()},
+	}
+}
+
+func (sdexProcessor) Filter(tx ingest.LedgerTransaction) bool {
+	if !tx.Successful() {
+		return false
+	}
+	for _, op := range tx.Envelope.Operations() {
+		switch op.Body.Type {
+		case xdr.OperationTypeManageBuyOffer, xdr.OperationTypeManageSellOffer, xdr.OperationTypeCreatePassiveSellOffer:
+			return true
+		}
+	}
+	return false
+}
+
+func (sdexProcessor) Process(ctx context.Context, tx ingest.LedgerTransaction, ledgerMeta xdr.LedgerCloseMeta) ([]models.Row, error) {
+	seq := ledgerMeta.LedgerSequence()
+	blockTime := ledgerMeta.ClosedAt()
+	opResults := tx.Result.Result.Result.Results
+
+	var rows []models.Row
+	for opIndex, op := range tx.Envelope.Operations() {
+		opCtx := logging.With(ctx, logging.KeyOpIndex, opIndex)
+		switch op.Body.Type {
+		case xdr.OperationTypeManageBuyOffer:
+			rows = append(rows, HandleManageBuyTransaction(opCtx, tx, op, seq, opIndex, opResults, blockTime)...)
+		case xdr.OperationTypeManageSellOffer:
+			rows = append(rows, HandleManageSellTransaction(opCtx, tx, op, seq, opIndex, opResults, blockTime)...)
+		case xdr.OperationTypeCreatePassiveSellOffer:
+			rows = append(rows, HandleCreatePassiveSellOffer(opCtx, tx, op, seq, opIndex, opResults, blockTime)...)
+		}
+	}
+	return rows, nil
+}
+
 func HandleManageBuyTransaction(
+	ctx context.Context,
 	tx ingest.LedgerTransaction,
 	op xdr.Operation,
 	seq uint32,
 	opIndex int,
 	results *[]xdr.OperationResult,
 	blockTime time.Time,
-) {
+) []models.Row {
 	offer := op.Body.MustManageBuyOfferOp()
 	if results == nil || opIndex >= len(*results) {
-		return
+		return nil
 	}
 
 	result := (*results)[opIndex].Tr.ManageBuyOfferResult
 	if result == nil {
-		return
+		return nil
 	}
 
 	if result.Code == xdr.ManageBuyOfferResultCodeManageBuyOfferSuccess {
@@ -39,7 +94,7 @@ func HandleManageBuyTransaction(
 			TransactionHash: tx.Result.TransactionHash.HexString(),
 			OperationIndex:  opIndex,
 			DexName:         utils.DEX_NAME_STELLAR_DEX,
-			SourceAccount:   op.SourceAccount.Address(),
+			SourceAccount:   operationSource(tx, op),
 			TokenIn:         utils.FormatAsset(offer.Buying),
 			TokenOut:        utils.FormatAsset(offer.Selling),
 			OfferBuyAmount:  float64(offer.BuyAmount) / 1e7,
@@ -65,45 +120,91 @@ func HandleManageBuyTransaction(
 				AmountSold:   float64(claim.AmountSold()) / 1e7,
 				AssetBought:  utils.FormatAsset(claim.AssetBought()),
 				AssetSold:    utils.FormatAsset(claim.AssetSold()),
-				Owner:        claim.SellerId().Address(),
-				OfferID:      uint64(claim.OfferId()),
+			}
+			match.Owner, match.OfferID = claimMaker(claim)
+			if claim.Type == xdr.ClaimAtomTypeClaimAtomTypeLiquidityPool {
+				match.OrderType = "liquidity_pool"
 			}
 			clean_tx.OrderMatches = append(clean_tx.OrderMatches, match)
 		}
-		// if clean_tx.Status == utils.ORDERBOOK_TX_STATUS_MATCHED || clean_tx.Status == utils.ORDERBOOK_TX_STATUS_PARTIALLY_MATCHED {
-		// 	fmt.Printf("Tx hash: %v ||||| total number of matches: %v |||| status : %v\n", clean_tx.TransactionHash, numMatches, clean_tx.Status)
-		// 	utils.PrettyPrintTransaction(clean_tx)
-		// }
+		logging.From(ctx).Debug("Manage buy offer", "status", clean_tx.Status, "matches", numMatches)
+		rows := []models.Row{clean_tx}
+		rows = append(rows, claimTrades(tx, op, seq, opIndex, success.OffersClaimed, blockTime)...)
 		token_buying_split := strings.Split(utils.FormatAsset(offer.Buying), ":")
-		token_selling_split := strings.Split(utils.FormatAsset(offer.Buying), ":")
+		token_selling_split := strings.Split(utils.FormatAsset(offer.Selling), ":")
 		if len(token_buying_split) > 1 {
-			go AddTokenData(token_buying_split[1])
+			rows = append(rows, tokenRows(ctx, seq, token_buying_split[1])...)
 		}
 		if len(token_selling_split) > 1 {
-			go AddTokenData(token_selling_split[1])
+			rows = append(rows, tokenRows(ctx, seq, token_selling_split[1])...)
 		}
-		utils.InsertTransactionsToDb([]models.TransactionModels{clean_tx})
+		return rows
 	}
+	return nil
 }
 
 func HandleManageSellTransaction(
+	ctx context.Context,
 	tx ingest.LedgerTransaction,
 	op xdr.Operation,
 	seq uint32,
 	opIndex int,
 	results *[]xdr.OperationResult,
 	blockTime time.Time,
-) {
+) []models.Row {
 	offer := op.Body.MustManageSellOfferOp()
 	if results == nil || opIndex >= len(*results) {
-		return
+		return nil
 	}
 
 	result := (*results)[opIndex].Tr.ManageSellOfferResult
 	if result == nil {
-		return
+		return nil
 	}
+	return manageSellRows(ctx, tx, op, seq, opIndex, offer, *result, blockTime, false)
+}
 
+// HandleCreatePassiveSellOffer records a passive sell offer. It takes and
+// rests like a manage sell offer, except that it never crosses an offer at
+// exactly its price, and is flagged passive.
+func HandleCreatePassiveSellOffer(
+	ctx context.Context,
+	tx ingest.LedgerTransaction,
+	op xdr.Operation,
+	seq uint32,
+	opIndex int,
+	results *[]xdr.OperationResult,
+	blockTime time.Time,
+) []models.Row {
+	passive := op.Body.MustCreatePassiveSellOfferOp()
+	if results == nil || opIndex >= len(*results) {
+		return nil
+	}
+
+	result := (*results)[opIndex].Tr.CreatePassiveSellOfferResult
+	if result == nil {
+		return nil
+	}
+	offer := xdr.ManageSellOfferOp{
+		Selling: passive.Selling,
+		Buying:  passive.Buying,
+		Amount:  passive.Amount,
+		Price:   passive.Price,
+	}
+	return manageSellRows(ctx, tx, op, seq, opIndex, offer, *result, blockTime, true)
+}
+
+func manageSellRows(
+	ctx context.Context,
+	tx ingest.LedgerTransaction,
+	op xdr.Operation,
+	seq uint32,
+	opIndex int,
+	offer xdr.ManageSellOfferOp,
+	result xdr.ManageSellOfferResult,
+	blockTime time.Time,
+	passive bool,
+) []models.Row {
 	if result.Code == xdr.ManageSellOfferResultCodeManageSellOfferSuccess {
 		success := result.Success
 		numMatches := len(success.OffersClaimed)
@@ -115,12 +216,13 @@ func HandleManageSellTransaction(
 			TransactionHash: tx.Result.TransactionHash.HexString(),
 			OperationIndex:  opIndex,
 			DexName:         utils.DEX_NAME_STELLAR_DEX,
-			SourceAccount:   op.SourceAccount.Address(),
+			SourceAccount:   operationSource(tx, op),
 			TokenIn:         utils.FormatAsset(offer.Buying),
 			TokenOut:        utils.FormatAsset(offer.Selling),
 			OfferSellAmount: float64(offer.Amount) / 1e7,
 			OfferBuyAmount:  (float64(offer.Amount) / 1e7) * (float64(offer.Price.N) / float64(offer.Price.D)),
 			OfferPrice:      float64(offer.Price.N) / float64(offer.Price.D),
+			Passive:         passive,
 		}
 
 		// Determine status
@@ -141,25 +243,26 @@ func HandleManageSellTransaction(
 				AmountSold:   float64(claim.AmountSold()) / 1e7,
 				AssetBought:  utils.FormatAsset(claim.AssetBought()),
 				AssetSold:    utils.FormatAsset(claim.AssetSold()),
-				Owner:        claim.SellerId().Address(),
-				OfferID:      uint64(claim.OfferId()),
+			}
+			match.Owner, match.OfferID = claimMaker(claim)
+			if claim.Type == xdr.ClaimAtomTypeClaimAtomTypeLiquidityPool {
+				match.OrderType = "liquidity_pool"
 			}
 			clean_tx.OrderMatches = append(clean_tx.OrderMatches, match)
 		}
 
-		// Print or persist
-		// if clean_tx.Status == utils.ORDERBOOK_TX_STATUS_MATCHED || clean_tx.Status == utils.ORDERBOOK_TX_STATUS_PARTIALLY_MATCHED {
-		// 	fmt.Printf("Tx hash: %v ||||| total matches: %v |||| status: %v\n", clean_tx.TransactionHash, numMatches, clean_tx.Status)
-		// 	utils.PrettyPrintTransaction(clean_tx)
-		// }
+		logging.From(ctx).Debug("Manage sell offer", "status", clean_tx.Status, "matches", numMatches, "passive", passive)
+		rows := []models.Row{clean_tx}
+		rows = append(rows, claimTrades(tx, op, seq, opIndex, success.OffersClaimed, blockTime)...)
 		token_buying_split := strings.Split(utils.FormatAsset(offer.Buying), ":")
-		token_selling_split := strings.Split(utils.FormatAsset(offer.Buying), ":")
+		token_selling_split := strings.Split(utils.FormatAsset(offer.Selling), ":")
 		if len(token_buying_split) > 1 {
-			go AddTokenData(token_buying_split[1])
+			rows = append(rows, tokenRows(ctx, seq, token_buying_split[1])...)
 		}
 		if len(token_selling_split) > 1 {
-			go AddTokenData(token_selling_split[1])
+			rows = append(rows, tokenRows(ctx, seq, token_selling_split[1])...)
 		}
-		utils.InsertTransactionsToDb([]models.TransactionModels{clean_tx})
+		return rows
 	}
+	return nil
 }
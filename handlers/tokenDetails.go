@@ -1,31 +1,39 @@
 package tx_handlers
 
 import (
-	"fmt"
+	"context"
 	"strings"
 
+	"github.com/celerfi/stellar-indexer-go/logging"
 	"github.com/celerfi/stellar-indexer-go/models"
 	"github.com/celerfi/stellar-indexer-go/utils"
 )
 
-func AddTokenData(tokenHash string) {
-	if utils.TokenExistsInDb(tokenHash) {
-		return
-	}
+// tokenRows returns a token_info row for every token hash this process has
+// not seen before, claiming it for ledger seq. See seenRows.
+func tokenRows(ctx context.Context, seq uint32, tokenHashes ...string) []models.Row {
+	var rows []models.Row
+	for _, tokenHash := range tokenHashes {
+		if !seenTokens.claim(tokenHash, seq) {
+			continue
+		}
 
-	var token *models.TokenInfo
-	var err error
+		var token *models.TokenInfo
+		var err error
 
-	if strings.HasPrefix(tokenHash, "C") {
-		token, err = utils.GetSorobanTokenInfo(tokenHash)
-	} else {
-		token, err = utils.GetClassicTokenInfo(tokenHash)
-	}
+		if strings.HasPrefix(tokenHash, "C") {
+			token, err = utils.GetSorobanTokenInfo(tokenHash)
+		} else {
+			token, err = utils.GetClassicTokenInfo(tokenHash)
+		}
 
-	if err != nil {
-		fmt.Printf("failed to get token info for %s: %v\n", tokenHash, err)
-		return
+		if err != nil {
+			// Forget the token so a later transaction tries again.
+			seenTokens.release(tokenHash, seq)
+			logging.From(ctx).Warn("Failed to get token info", "token", tokenHash, logging.Err(err))
+			continue
+		}
+		rows = append(rows, *token)
 	}
-
-	go utils.SaveTokenToDB(*token)
+	return rows
 }
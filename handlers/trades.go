@@ -0,0 +1,115 @@
+package tx_handlers
+
+import (
+	"time"
+
+	"github.com/celerfi/stellar-indexer-go/models"
+	"github.com/celerfi/stellar-indexer-go/utils"
+	"github.com/stellar/go/ingest"
+	"github.com/stellar/go/strkey"
+	"github.com/stellar/go/xdr"
+)
+
+// claimTrades turns the offers an operation claimed into one trade per
+// claim. The operation's source is the taker; each claimed offer's seller, or
+// the liquidity pool it traded against, is the maker.
+func claimTrades(
+	tx ingest.LedgerTransaction,
+	op xdr.Operation,
+	seq uint32,
+	opIndex int,
+	claims []xdr.ClaimAtom,
+	blockTime time.Time,
+) []models.Row {
+	var rows []models.Row
+	for claimIndex, claim := range claims {
+		// Offers that could no longer be filled are removed with an empty
+		// claim; nothing changed hands.
+		if claim.AmountSold() == 0 || claim.AmountBought() == 0 {
+			continue
+		}
+		trade := models.Trade{
+			BlockTime:       blockTime,
+			LedgerSequence:  seq,
+			TransactionHash: tx.Result.TransactionHash.HexString(),
+			OperationIndex:  opIndex,
+			ClaimIndex:      claimIndex,
+			OperationType:   op.Body.Type.String(),
+			TakerAccount:    operationSource(tx, op),
+		}
+		trade.MakerAccount, trade.MakerOfferID = claimMaker(claim)
+		if claim.Type == xdr.ClaimAtomTypeClaimAtomTypeLiquidityPool {
+			trade.LiquidityPoolID = trade.MakerAccount
+		}
+		setTradePair(&trade, claim)
+		rows = append(rows, trade)
+	}
+	return rows
+}
+
+// claimMaker is the counterparty of claim: the seller and ID of the claimed
+// offer, or the pool ID and no offer for a swap against a liquidity pool.
+func claimMaker(claim xdr.ClaimAtom) (string, uint64) {
+	if pool, ok := claim.GetLiquidityPool(); ok {
+		return liquidityPoolID(pool.LiquidityPoolId), 0
+	}
+	return claim.SellerId().Address(), uint64(claim.OfferId())
+}
+
+// setTradePair fills the pair, amounts and price of trade from claim. The
+// maker sold AssetSold and bought AssetBought.
+func setTradePair(trade *models.Trade, claim xdr.ClaimAtom) {
+	makerSold, makerBought := claim.AssetSold(), claim.AssetBought()
+	soldAmount, boughtAmount := claim.AmountSold(), claim.AmountBought()
+
+	trade.BaseIsSeller = makerSold.LessThan(makerBought)
+	if trade.BaseIsSeller {
+		trade.BaseAsset, trade.QuoteAsset = utils.FormatAsset(makerSold), utils.FormatAsset(makerBought)
+		trade.BaseAmount, trade.QuoteAmount = float64(soldAmount)/1e7, float64(boughtAmount)/1e7
+		trade.Price = float64(boughtAmount) / float64(soldAmount)
+	} else {
+		trade.BaseAsset, trade.QuoteAsset = utils.FormatAsset(makerBought), utils.FormatAsset(makerSold)
+		trade.BaseAmount, trade.QuoteAmount = float64(boughtAmount)/1e7, float64(soldAmount)/1e7
+		trade.Price = float64(soldAmount) / float64(boughtAmount)
+	}
+}
+
+// liquidityPoolID is the strkey (L...) form of a classic liquidity pool ID.
+func liquidityPoolID(id xdr.PoolId) string {
+	return strkey.MustEncode(strkey.VersionByteLiquidityPool, id[:])
+}
+
+// operationClaims returns the offers and pool reserves the operation at
+// opIndex crossed, for the operations that trade.
+func operationClaims(results *[]xdr.OperationResult, opIndex int) []xdr.ClaimAtom {
+	if results == nil || opIndex >= len(*results) {
+		return nil
+	}
+	tr, ok := (*results)[opIndex].GetTr()
+	if !ok {
+		return nil
+	}
+	switch tr.Type {
+	case xdr.OperationTypeManageSellOffer:
+		if success, ok := tr.MustManageSellOfferResult().GetSuccess(); ok {
+			return success.OffersClaimed
+		}
+	case xdr.OperationTypeCreatePassiveSellOffer:
+		if success, ok := tr.MustCreatePassiveSellOfferResult().GetSuccess(); ok {
+			return success.OffersClaimed
+		}
+	case xdr.OperationTypeManageBuyOffer:
+		if success, ok := tr.MustManageBuyOfferResult().GetSuccess(); ok {
+			return success.OffersClaimed
+		}
+	case xdr.OperationTypePathPaymentStrictSend:
+		if success, ok := tr.MustPathPaymentStrictSendResult().GetSuccess(); ok {
+			return success.Offers
+		}
+	case xdr.OperationTypePathPaymentStrictReceive:
+		if success, ok := tr.MustPathPaymentStrictReceiveResult().GetSuccess(); ok {
+			return success.Offers
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	tx_handlers "github.com/celerfi/stellar-indexer-go/handlers"
+)
+
+var state struct {
+	lock          sync.RWMutex
+	lastCommitted uint32
+	committedAt   time.Time
+	fatalErr      error
+	latest        uint32
+	latestErr     error
+}
+
+// RecordCommit notes that ingestion committed seq.
+func RecordCommit(seq uint32) {
+	state.lock.Lock()
+	defer state.lock.Unlock()
+	state.lastCommitted = seq
+	state.committedAt = time.Now()
+}
+
+// RecordLatestLedger notes the source's newest ledger, or why it could not
+// be read. /readyz measures the lag against it rather than asking the
+// source on every probe.
+func RecordLatestLedger(seq uint32, err error) {
+	state.lock.Lock()
+	defer state.lock.Unlock()
+	state.latestErr = err
+	if err == nil {
+		state.latest = seq
+	}
+}
+
+// RecordFailure marks ingestion as broken; /healthz fails from then on.
+func RecordFailure(err error) {
+	state.lock.Lock()
+	defer state.lock.Unlock()
+	state.fatalErr = err
+}
+
+type check struct {
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type report struct {
+	Status string           `json:"status"`
+	Checks map[string]check `json:"checks"`
+}
+
+// Healthz reports whether the ingestion loop is still alive.
+func Healthz(w http.ResponseWriter, r *http.Request) {
+	state.lock.RLock()
+	fatalErr := state.fatalErr
+	state.lock.RUnlock()
+
+	checks := map[string]check{"ingestion": {OK: true}}
+	if fatalErr != nil {
+		checks["ingestion"] = check{OK: false, Detail: fatalErr.Error()}
+	}
+	writeReport(w, checks)
+}
+
+// Readyz reports whether the indexer is caught up and its dependencies work:
+// the last committed ledger is within maxLag of the source's latest ledger
+// as last recorded, pingSink succeeds, and every Reflector asset list
+// loaded.
+func Readyz(maxLag uint32, pingSink func(context.Context) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		checks := map[string]check{
+			"ledger_lag": lagCheck(maxLag),
+			"sink":       sinkCheck(ctx, pingSink),
+			"reflector":  reflectorCheck(),
+		}
+		writeReport(w, checks)
+	}
+}
+
+func lagCheck(maxLag uint32) check {
+	state.lock.RLock()
+	lastCommitted, latest, err := state.lastCommitted, state.latest, state.latestErr
+	state.lock.RUnlock()
+
+	if lastCommitted == 0 {
+		return check{OK: false, Detail: "no ledger committed yet"}
+	}
+	if err != nil {
+		return check{OK: false, Detail: fmt.Sprintf("latest ledger unavailable: %v", err)}
+	}
+	if latest == 0 {
+		return check{OK: false, Detail: "latest ledger not known yet"}
+	}
+
+	lag := int64(latest) - int64(lastCommitted)
+	detail := fmt.Sprintf("committed %d, latest %d, lag %d (max %d)", lastCommitted, latest, lag, maxLag)
+	return check{OK: lag <= int64(maxLag), Detail: detail}
+}
+
+func sinkCheck(ctx context.Context, pingSink func(context.Context) error) check {
+	if err := pingSink(ctx); err != nil {
+		return check{OK: false, Detail: err.Error()}
+	}
+	return check{OK: true}
+}
+
+func reflectorCheck() check {
+	loaded, expected := tx_handlers.ReflectorAssetsLoaded()
+	return check{OK: loaded == expected, Detail: fmt.Sprintf("%d of %d asset lists loaded", loaded, expected)}
+}
+
+func writeReport(w http.ResponseWriter, checks map[string]check) {
+	rep := report{Status: "ok", Checks: checks}
+	code := http.StatusOK
+	for _, c := range checks {
+		if !c.OK {
+			rep.Status = "unavailable"
+			code = http.StatusServiceUnavailable
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(rep)
+}
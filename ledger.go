@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/celerfi/stellar-indexer-go/config"
+	tx_handlers "github.com/celerfi/stellar-indexer-go/handlers"
+	"github.com/celerfi/stellar-indexer-go/ledgersource"
+	"github.com/celerfi/stellar-indexer-go/logging"
+	"github.com/celerfi/stellar-indexer-go/metrics"
+	"github.com/celerfi/stellar-indexer-go/models"
+	"github.com/celerfi/stellar-indexer-go/pipeline"
+	"github.com/celerfi/stellar-indexer-go/sink"
+	"github.com/stellar/go/ingest"
+	"github.com/stellar/go/ingest/ledgerbackend"
+	"github.com/stellar/go/xdr"
+)
+
+// errUndecodableLedger marks ledgers whose transactions cannot be read.
+// Retrying them cannot help, so they are recorded as dead letters.
+var errUndecodableLedger = errors.New("undecodable ledger")
+
+// errProcessorFailed marks ledgers on which a processor returned an error or
+// panicked. Committing the rest of their rows would leave the processor's
+// rows silently missing, so they are recorded as dead letters to be
+// reprocessed once the processor is fixed.
+var errProcessorFailed = errors.New("processor failed")
+
+// isDeadLetter tells whether err from processLedger means the ledger goes to
+// the dead letters rather than stopping ingestion.
+func isDeadLetter(err error) bool {
+	return errors.Is(err, errUndecodableLedger) || errors.Is(err, errProcessorFailed)
+}
+
+// indexLedger processes ledger and writes its rows to out under cursorName.
+// A ledger that cannot be decoded or that a processor failed on is recorded
+// as a dead letter instead and the cursor moves past it.
+func indexLedger(ctx context.Context, out sink.Sink, pool *pipeline.Pool, registry *tx_handlers.Registry, passphrase, cursorName string, ledger xdr.LedgerCloseMeta) error {
+	batch, err := processLedger(ctx, pool, registry, passphrase, ledger)
+	if err != nil {
+		tx_handlers.SettleLedger(ledger.LedgerSequence(), false)
+	}
+	if isDeadLetter(err) {
+		slog.Error("Recording ledger as dead letter", logging.KeyLedger, ledger.LedgerSequence(), logging.Err(err))
+		return out.WriteDeadLetter(ctx, ledger.LedgerSequence(), rawMeta(ledger), cursorName, err)
+	}
+	if err != nil {
+		return err
+	}
+	err = out.WriteLedger(ctx, batch, cursorName)
+	tx_handlers.SettleLedger(ledger.LedgerSequence(), err == nil)
+	return err
+}
+
+// fetchLedger gets seq from backend for indexLedger. A ledger whose data is
+// corrupt is recorded as a dead letter under cursorName on drainCtx instead,
+// and ok is false so the caller moves on to the next ledger.
+func fetchLedger(ctx, drainCtx context.Context, out sink.Sink, backend ledgerbackend.LedgerBackend, seq uint32, policy config.RetryConfig, cursorName string) (ledger xdr.LedgerCloseMeta, ok bool, err error) {
+	ledger, err = ledgersource.GetLedger(ctx, backend, seq, policy)
+	var corrupt *ledgersource.CorruptLedgerError
+	if errors.As(err, &corrupt) {
+		slog.Error("Recording corrupt ledger as dead letter", logging.KeyLedger, seq, logging.Err(err))
+		return ledger, false, out.WriteDeadLetter(drainCtx, seq, corrupt.Raw, cursorName, err)
+	}
+	return ledger, err == nil, err
+}
+
+// rawMeta is the XDR of ledger kept with its dead letter, nil when the meta
+// cannot be encoded again.
+func rawMeta(ledger xdr.LedgerCloseMeta) []byte {
+	raw, _ := ledger.MarshalBinary()
+	return raw
+}
+
+// processLedger runs every enabled processor that matches a transaction of
+// the ledger on the pool and returns the derived rows, along with the
+// ledger's own row, once all of them are done. Live ingestion and backfill
+// both go through here so their rows match. An errUndecodableLedger error
+// means the ledger itself could not be decoded, an errProcessorFailed error
+// that a processor failed on one of its transactions; the batch is then
+// incomplete and must not be written.
+func processLedger(ctx context.Context, pool *pipeline.Pool, registry *tx_handlers.Registry, passphrase string, ledger xdr.LedgerCloseMeta) (models.LedgerBatch, error) {
+	seq := ledger.LedgerSequence()
+	ctx = logging.With(ctx, logging.KeyLedger, seq)
+	tx_reader, err := ingest.NewLedgerTransactionReaderFromLedgerCloseMeta(passphrase, ledger)
+	if err != nil {
+		return models.LedgerBatch{}, fmt.Errorf("%w: failed to create transaction reader for ledger %d: %w", errUndecodableLedger, seq, err)
+	}
+	defer tx_reader.Close()
+
+	logging.From(ctx).Info("Processing ledger", "transactions", ledger.CountTransactions())
+
+	ledgerRow := tx_handlers.NewLedgerRow(ledger)
+	ledgerJob := pipeline.NewLedger(seq)
+	for {
+		tx, readErr := tx_reader.Read()
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+		if readErr != nil {
+			// Tasks already queued still hold the barrier; let them drain.
+			ledgerJob.Wait()
+			return models.LedgerBatch{}, fmt.Errorf("%w: error reading transaction in ledger %d: %w", errUndecodableLedger, seq, readErr)
+		}
+		tx_handlers.CountTransaction(&ledgerRow, tx)
+		txCtx := logging.With(ctx, logging.KeyTxHash, tx.Result.TransactionHash.HexString())
+		for opIndex, op := range tx.Envelope.Operations() {
+			metrics.OperationsSeen.WithLabelValues(op.Body.Type.String()).Inc()
+			logging.From(txCtx).Debug("Found operation", logging.KeyOpIndex, opIndex, "op_type", op.Body.Type.String())
+		}
+		for _, processor := range registry.Processors() {
+			if !processor.Filter(tx) {
+				continue
+			}
+			task := func() (models.LedgerBatch, error) {
+				var batch models.LedgerBatch
+				procCtx := logging.With(txCtx, logging.KeyProcessor, processor.Name())
+				rows, err := registry.Process(procCtx, processor, tx, ledger)
+				if err != nil {
+					logging.From(procCtx).Error("Processor failed", logging.Err(err))
+				}
+				batch.Add(rows...)
+				return batch, err
+			}
+			if err := pool.Submit(ctx, ledgerJob, task); err != nil {
+				ledgerJob.Wait()
+				return models.LedgerBatch{}, fmt.Errorf("failed to queue processor %s for ledger %d: %w", processor.Name(), seq, err)
+			}
+		}
+	}
+
+	// Every processor for the ledger has finished or failed past this point.
+	batch, err := ledgerJob.Wait()
+	if err != nil {
+		return models.LedgerBatch{}, fmt.Errorf("%w in ledger %d: %w", errProcessorFailed, seq, err)
+	}
+	batch.Ledger = &ledgerRow
+	return batch, nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	tx_handlers "github.com/celerfi/stellar-indexer-go/handlers"
+	"github.com/celerfi/stellar-indexer-go/models"
+	"github.com/celerfi/stellar-indexer-go/pipeline"
+	"github.com/celerfi/stellar-indexer-go/sink"
+	"github.com/stellar/go/ingest"
+	"github.com/stellar/go/network"
+	"github.com/stellar/go/xdr"
+)
+
+func init() {
+	tx_handlers.Register(failingProcessor{})
+}
+
+// failWith is what failingProcessor does to every transaction. It matches
+// none while nil, so other tests never see it.
+var failWith func() error
+
+// failingProcessor stands in for a processor with a bug.
+type failingProcessor struct{}
+
+func (failingProcessor) Name() string                            { return "failing" }
+func (failingProcessor) Scopes() []models.RowScope               { return nil }
+func (failingProcessor) Filter(tx ingest.LedgerTransaction) bool { return failWith != nil }
+
+func (failingProcessor) Process(ctx context.Context, tx ingest.LedgerTransaction, ledgerMeta xdr.LedgerCloseMeta) ([]models.Row, error) {
+	return nil, failWith()
+}
+
+// TestIndexLedgerProcessorFailure checks that a ledger a processor fails on
+// is dead-lettered with the cursor moved past it, rather than committed
+// without that processor's rows.
+func TestIndexLedgerProcessorFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		fail func() error
+	}{
+		{"error", func() error { return errors.New("bad transaction") }},
+		{"panic", func() error { panic("index out of range") }},
+	}
+	registry, err := newRegistry([]string{"sdex", "failing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := pipeline.NewPool(2, 8)
+	defer pool.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failWith = tt.fail
+			defer func() { failWith = nil }()
+
+			ctx := context.Background()
+			out := sink.NewMemory()
+			if err := indexLedger(ctx, out, pool, registry, network.TestNetworkPassphrase, "test", sdexFixture()); err != nil {
+				t.Fatal(err)
+			}
+			if batches := out.Batches(); len(batches) != 0 {
+				t.Errorf("got %d committed batches, want none", len(batches))
+			}
+			if got := out.DeadLetters(); len(got) != 1 || got[0] != fixtureSDEXLedger {
+				t.Errorf("dead letters = %v, want [%d]", got, fixtureSDEXLedger)
+			}
+			if cursor, _ := out.Cursor(ctx, "test"); cursor != fixtureSDEXLedger {
+				t.Errorf("cursor = %d, want %d", cursor, fixtureSDEXLedger)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package ledgersource
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/stellar/go/ingest/ledgerbackend"
+	"github.com/stellar/go/support/compressxdr"
+	"github.com/stellar/go/xdr"
+)
+
+// ErrLedgerNotFound is returned by FileBackend when the requested ledger has
+// no file in the directory. For an offline replay this marks the end of input.
+var ErrLedgerNotFound = errors.New("ledger file not found")
+
+// Ensure FileBackend implements LedgerBackend
+var _ ledgerbackend.LedgerBackend = (*FileBackend)(nil)
+
+// FileBackend serves ledgers from a local directory where every file holds a
+// single XDR encoded LedgerCloseMeta named after its sequence, either raw
+// (<seq>.xdr) or zstd compressed (<seq>.xdr.zst).
+type FileBackend struct {
+	dir string
+
+	lock     sync.RWMutex
+	prepared *ledgerbackend.Range
+	closed   bool
+}
+
+func NewFileBackend(dir string) (*FileBackend, error) {
+	if dir == "" {
+		return nil, errors.New("LEDGER_DIR must be set for the file ledger source")
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ledger directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("ledger directory %s is not a directory", dir)
+	}
+	return &FileBackend{dir: dir}, nil
+}
+
+func (b *FileBackend) GetLatestLedgerSequence(ctx context.Context) (uint32, error) {
+	entries, err := os.ReadDir(b.dir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read ledger directory: %w", err)
+	}
+
+	var latest uint32
+	for _, entry := range entries {
+		seq, ok := sequenceFromFileName(entry.Name())
+		if ok && seq > latest {
+			latest = seq
+		}
+	}
+	if latest == 0 {
+		return 0, fmt.Errorf("no ledger files in %s", b.dir)
+	}
+	return latest, nil
+}
+
+func (b *FileBackend) GetLedger(ctx context.Context, sequence uint32) (xdr.LedgerCloseMeta, error) {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	if b.closed {
+		return xdr.LedgerCloseMeta{}, errors.New("FileBackend is closed")
+	}
+	if b.prepared == nil {
+		return xdr.LedgerCloseMeta{}, errors.New("FileBackend must be prepared before calling GetLedger")
+	}
+	if sequence < b.prepared.From() || (b.prepared.Bounded() && sequence > b.prepared.To()) {
+		return xdr.LedgerCloseMeta{}, fmt.Errorf("requested ledger %d is outside prepared range %s", sequence, b.prepared.String())
+	}
+
+	return b.readLedger(sequence)
+}
+
+func (b *FileBackend) PrepareRange(ctx context.Context, ledgerRange ledgerbackend.Range) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.closed {
+		return errors.New("FileBackend is closed")
+	}
+	if _, err := b.ledgerPath(ledgerRange.From()); err != nil {
+		return err
+	}
+	b.prepared = &ledgerRange
+	return nil
+}
+
+func (b *FileBackend) IsPrepared(ctx context.Context, ledgerRange ledgerbackend.Range) (bool, error) {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	if b.closed || b.prepared == nil {
+		return false, nil
+	}
+	return *b.prepared == ledgerRange, nil
+}
+
+func (b *FileBackend) Close() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.closed = true
+	return nil
+}
+
+func (b *FileBackend) readLedger(sequence uint32) (xdr.LedgerCloseMeta, error) {
+	var lcm xdr.LedgerCloseMeta
+
+	path, err := b.ledgerPath(sequence)
+	if err != nil {
+		return lcm, err
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return lcm, fmt.Errorf("failed to read ledger %d: %w", sequence, err)
+	}
+
+	if strings.HasSuffix(path, ".zst") {
+		decoder := compressxdr.NewXDRDecoder(compressxdr.DefaultCompressor, &lcm)
+		_, err = decoder.ReadFrom(bytes.NewReader(raw))
+	} else {
+		err = xdr.SafeUnmarshal(raw, &lcm)
+	}
+	if err == nil && lcm.LedgerSequence() != sequence {
+		err = fmt.Errorf("file holds ledger %d", lcm.LedgerSequence())
+	}
+	if err != nil {
+		return xdr.LedgerCloseMeta{}, &CorruptLedgerError{Sequence: sequence, Raw: raw, Err: err}
+	}
+	return lcm, nil
+}
+
+func (b *FileBackend) ledgerPath(sequence uint32) (string, error) {
+	name := strconv.FormatUint(uint64(sequence), 10) + ".xdr"
+	for _, candidate := range []string{name, name + ".zst"} {
+		path := filepath.Join(b.dir, candidate)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("%w: ledger %d in %s", ErrLedgerNotFound, sequence, b.dir)
+}
+
+func sequenceFromFileName(name string) (uint32, bool) {
+	name = strings.TrimSuffix(name, ".zst")
+	if !strings.HasSuffix(name, ".xdr") {
+		return 0, false
+	}
+	seq, err := strconv.ParseUint(strings.TrimSuffix(name, ".xdr"), 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return uint32(seq), true
+}
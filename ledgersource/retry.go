@@ -0,0 +1,83 @@
+package ledgersource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/celerfi/stellar-indexer-go/config"
+	"github.com/celerfi/stellar-indexer-go/logging"
+	"github.com/celerfi/stellar-indexer-go/metrics"
+	"github.com/stellar/go/ingest/ledgerbackend"
+	"github.com/stellar/go/xdr"
+)
+
+// GetLedger fetches sequence from backend, retrying transient failures with
+// exponential backoff and jitter. Ledgers that are not closed yet are waited
+// for by the backends themselves. Permanent errors (see IsPermanent) and
+// ctx cancellation are returned immediately; transient ones once
+// policy.MaxAttempts is used up.
+func GetLedger(ctx context.Context, backend ledgerbackend.LedgerBackend, sequence uint32, policy config.RetryConfig) (xdr.LedgerCloseMeta, error) {
+	for attempt := 1; ; attempt++ {
+		ledger, err := backend.GetLedger(ctx, sequence)
+		if err == nil {
+			return ledger, nil
+		}
+		if ctx.Err() != nil || IsPermanent(err) {
+			return ledger, err
+		}
+		if policy.MaxAttempts > 0 && attempt >= policy.MaxAttempts {
+			return ledger, err
+		}
+
+		delay := backoff(attempt, policy)
+		metrics.LedgerFetchRetries.Inc()
+		slog.Warn("Failed to get ledger, retrying", logging.KeyLedger, sequence,
+			"attempt", attempt, "delay", delay, logging.Err(err))
+		select {
+		case <-ctx.Done():
+			return ledger, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// CorruptLedgerError is returned for a ledger whose data was read but does
+// not decode. Fetching it again yields the same bytes, so callers record it
+// as a dead letter with Raw instead of retrying.
+type CorruptLedgerError struct {
+	Sequence uint32
+	Raw      []byte
+	Err      error
+}
+
+func (e *CorruptLedgerError) Error() string {
+	return fmt.Sprintf("failed to decode ledger %d: %v", e.Sequence, e.Err)
+}
+
+func (e *CorruptLedgerError) Unwrap() error { return e.Err }
+
+// IsPermanent reports whether retrying a fetch cannot help: the file source
+// ran out of ledgers, the RPC node no longer retains the ledger, or the
+// ledger's data is corrupt.
+func IsPermanent(err error) bool {
+	var missing *ledgerbackend.RPCLedgerMissingError
+	var corrupt *CorruptLedgerError
+	return errors.Is(err, ErrLedgerNotFound) || errors.As(err, &missing) || errors.As(err, &corrupt)
+}
+
+// backoff doubles the delay per attempt up to MaxDelay and picks a random
+// point in its upper half, so restarted indexers do not retry in lockstep.
+func backoff(attempt int, policy config.RetryConfig) time.Duration {
+	delay := policy.MaxDelay
+	if attempt < 32 {
+		if d := policy.BaseDelay << (attempt - 1); d > 0 && d < delay {
+			delay = d
+		}
+	}
+	half := delay / 2
+	return half + rand.N(half+1)
+}
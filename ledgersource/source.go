@@ -0,0 +1,112 @@
+package ledgersource
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/celerfi/stellar-indexer-go/config"
+	client "github.com/stellar/go/clients/rpcclient"
+	"github.com/stellar/go/ingest/ledgerbackend"
+	"github.com/stellar/go/support/datastore"
+)
+
+const (
+	SOURCE_RPC       = "rpc"
+	SOURCE_DATASTORE = "datastore"
+	SOURCE_FILE      = "file"
+)
+
+// New builds the ledger backend selected by cfg.Source.
+func New(ctx context.Context, cfg config.Config) (ledgerbackend.LedgerBackend, error) {
+	switch cfg.Source.Type {
+	case SOURCE_RPC:
+		return ledgerbackend.NewRPCLedgerBackend(ledgerbackend.RPCLedgerBackendOptions{
+			RPCServerURL: cfg.RPC.URL,
+		}), nil
+	case SOURCE_DATASTORE:
+		return newDatastoreBackend(ctx, cfg)
+	case SOURCE_FILE:
+		return NewFileBackend(cfg.Source.Dir)
+	default:
+		return nil, fmt.Errorf("unknown ledger source %q: options (rpc, datastore, file)", cfg.Source.Type)
+	}
+}
+
+// LatestLedger returns the newest ledger the configured source can serve.
+// It is used to pick a starting point when there is nothing to resume from.
+func LatestLedger(ctx context.Context, cfg config.Config) (uint32, error) {
+	switch cfg.Source.Type {
+	case SOURCE_RPC:
+		rpcClient := client.NewClient(cfg.RPC.URL, nil)
+		health, err := rpcClient.GetHealth(ctx)
+		if err != nil {
+			return 0, err
+		}
+		return health.LatestLedger, nil
+	case SOURCE_DATASTORE:
+		store, err := datastore.NewDataStore(ctx, datastoreConfig(cfg))
+		if err != nil {
+			return 0, fmt.Errorf("failed to open datastore: %w", err)
+		}
+		defer store.Close()
+		return datastore.FindLatestLedgerSequence(ctx, store)
+	case SOURCE_FILE:
+		backend, err := NewFileBackend(cfg.Source.Dir)
+		if err != nil {
+			return 0, err
+		}
+		return backend.GetLatestLedgerSequence(ctx)
+	default:
+		return 0, fmt.Errorf("unknown ledger source %q: options (rpc, datastore, file)", cfg.Source.Type)
+	}
+}
+
+func newDatastoreBackend(ctx context.Context, cfg config.Config) (ledgerbackend.LedgerBackend, error) {
+	dsConfig := datastoreConfig(cfg)
+	store, err := datastore.NewDataStore(ctx, dsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open datastore: %w", err)
+	}
+
+	schema, err := datastore.LoadSchema(ctx, store, dsConfig)
+	if err != nil {
+		store.Close()
+		return nil, fmt.Errorf("failed to load datastore schema: %w", err)
+	}
+
+	backend, err := ledgerbackend.NewBufferedStorageBackend(ledgerbackend.BufferedStorageBackendConfig{
+		BufferSize: 100,
+		NumWorkers: 10,
+		RetryLimit: 3,
+		RetryWait:  5 * time.Second,
+	}, store, schema)
+	if err != nil {
+		store.Close()
+		return nil, fmt.Errorf("failed to create buffered storage backend: %w", err)
+	}
+	return backend, nil
+}
+
+func datastoreConfig(cfg config.Config) datastore.DataStoreConfig {
+	ds := cfg.Source.Datastore
+	params := map[string]string{
+		"destination_bucket_path": ds.BucketPath,
+	}
+	if ds.Region != "" {
+		params["region"] = ds.Region
+	}
+	if ds.EndpointURL != "" {
+		params["endpoint_url"] = ds.EndpointURL
+	}
+
+	return datastore.DataStoreConfig{
+		Type:              ds.Type,
+		Params:            params,
+		NetworkPassphrase: cfg.Network.Passphrase,
+		Schema: datastore.DataStoreSchema{
+			LedgersPerFile:    ds.LedgersPerFile,
+			FilesPerPartition: ds.FilesPerPartition,
+		},
+	}
+}
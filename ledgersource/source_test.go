@@ -0,0 +1,117 @@
+package ledgersource
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/celerfi/stellar-indexer-go/config"
+	"github.com/celerfi/stellar-indexer-go/stellartest"
+	"github.com/stellar/go/ingest/ledgerbackend"
+	"github.com/stellar/go/xdr"
+)
+
+// fixtureLedgers are the captured ledgers the golden tests replay.
+const fixtureLedgers = "../testdata/ledgers"
+
+func loadFixtures(t *testing.T, seqs ...uint32) []xdr.LedgerCloseMeta {
+	t.Helper()
+	ctx := context.Background()
+	var ledgers []xdr.LedgerCloseMeta
+	for _, seq := range seqs {
+		backend, err := NewFileBackend(fixtureLedgers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := backend.PrepareRange(ctx, ledgerbackend.BoundedRange(seq, seq)); err != nil {
+			t.Fatal(err)
+		}
+		ledger, err := backend.GetLedger(ctx, seq)
+		if err != nil {
+			t.Fatal(err)
+		}
+		backend.Close()
+		ledgers = append(ledgers, ledger)
+	}
+	return ledgers
+}
+
+func rpcConfig(fake *stellartest.Server) config.Config {
+	cfg := config.Default()
+	cfg.Source.Type = SOURCE_RPC
+	cfg.RPC.URL = fake.RPCURL()
+	return cfg
+}
+
+func TestLatestLedgerRPC(t *testing.T) {
+	fake := stellartest.NewServer()
+	defer fake.Close()
+	fake.SetLatestLedger(54_321)
+
+	latest, err := LatestLedger(context.Background(), rpcConfig(fake))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest != 54_321 {
+		t.Errorf("latest ledger = %d, want 54321", latest)
+	}
+}
+
+func TestRPCSourceGetLedger(t *testing.T) {
+	fake := stellartest.NewServer()
+	defer fake.Close()
+	for _, ledger := range loadFixtures(t, 1000, 2000, 3000) {
+		fake.AddLedger(ledger)
+	}
+	want := loadFixtures(t, 2000)[0]
+
+	ctx := context.Background()
+	backend, err := New(ctx, rpcConfig(fake))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+	if err := backend.PrepareRange(ctx, ledgerbackend.BoundedRange(2000, 2000)); err != nil {
+		t.Fatal(err)
+	}
+	got, err := backend.GetLedger(ctx, 2000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.LedgerHash() != want.LedgerHash() || got.CountTransactions() != want.CountTransactions() {
+		t.Errorf("got ledger %s with %d txs, want %s with %d",
+			got.LedgerHash().HexString(), got.CountTransactions(), want.LedgerHash().HexString(), want.CountTransactions())
+	}
+	if n := fake.Calls("getLedgers"); n != 1 {
+		t.Errorf("getLedgers called %d times, want 1", n)
+	}
+}
+
+func TestFileBackendCorruptLedger(t *testing.T) {
+	dir := t.TempDir()
+	raw := []byte("not a ledger")
+	if err := os.WriteFile(filepath.Join(dir, "7.xdr"), raw, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	backend, err := NewFileBackend(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+	ctx := context.Background()
+	if err := backend.PrepareRange(ctx, ledgerbackend.BoundedRange(7, 7)); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.Default()
+	_, err = GetLedger(ctx, backend, 7, cfg.Source.Retry)
+	var corrupt *CorruptLedgerError
+	if !errors.As(err, &corrupt) || !IsPermanent(err) {
+		t.Fatalf("err = %v, want a permanent CorruptLedgerError", err)
+	}
+	if corrupt.Sequence != 7 || string(corrupt.Raw) != string(raw) {
+		t.Errorf("got ledger %d with %q, want 7 with the file's bytes", corrupt.Sequence, corrupt.Raw)
+	}
+}
@@ -0,0 +1,72 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Field names shared by every log line so they can be queried across
+// components.
+const (
+	KeyLedger    = "ledger_seq"
+	KeyTxHash    = "tx_hash"
+	KeyOpIndex   = "op_index"
+	KeyProcessor = "processor"
+	KeyError     = "error"
+)
+
+// Setup installs the default logger. level is debug, info (default), warn or
+// error; format is text (default) or json.
+func Setup(level, format string) error {
+	logger, err := New(os.Stderr, level, format)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+	return nil
+}
+
+// New builds a logger writing to w with the given level and format.
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if level != "" {
+		if err := lvl.UnmarshalText([]byte(level)); err != nil {
+			return nil, fmt.Errorf("unknown LOG_LEVEL %q: options (debug, info, warn, error)", level)
+		}
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch strings.ToLower(format) {
+	case "", "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("unknown LOG_FORMAT %q: options (text, json)", format)
+	}
+}
+
+// Err is the attribute errors are logged under.
+func Err(err error) slog.Attr {
+	return slog.Any(KeyError, err)
+}
+
+type loggerKey struct{}
+
+// With returns a context whose logger carries args in addition to the fields
+// already attached to ctx.
+func With(ctx context.Context, args ...any) context.Context {
+	return context.WithValue(ctx, loggerKey{}, From(ctx).With(args...))
+}
+
+// From returns the logger attached to ctx, or the default logger.
+func From(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
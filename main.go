@@ -4,103 +4,243 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io"
-	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/celerfi/stellar-indexer-go/config"
 	tx_handlers "github.com/celerfi/stellar-indexer-go/handlers"
+	"github.com/celerfi/stellar-indexer-go/health"
+	"github.com/celerfi/stellar-indexer-go/ledgersource"
+	"github.com/celerfi/stellar-indexer-go/logging"
+	"github.com/celerfi/stellar-indexer-go/metrics"
+	"github.com/celerfi/stellar-indexer-go/pipeline"
+	"github.com/celerfi/stellar-indexer-go/sink"
 	"github.com/celerfi/stellar-indexer-go/utils"
-	"github.com/stellar/go/ingest"
 	"github.com/stellar/go/ingest/ledgerbackend"
-	"github.com/stellar/go/network"
-	"github.com/stellar/go/xdr"
 )
 
 func main() {
-	ctx := context.Background()
-	fmt.Println("CelarFi Indexer: Starting up...")
-	fmt.Println("Chain: Stellar")
+	cfg, args, err := config.Load(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		os.Exit(2)
+	}
+	if err := logging.Setup(cfg.Log.Level, cfg.Log.Format); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	// SIGINT / SIGTERM cancel ctx: no new ledgers are fetched and the one in
+	// flight finishes on drainCtx. A second signal kills the process.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	context.AfterFunc(ctx, stop)
+	drainCtx := drainContext(ctx, cfg.ShutdownTimeout)
+
+	slog.Info("CelarFi Indexer: Starting up", "chain", "stellar", "network", cfg.Network.Name)
+	slog.Info("Loaded configuration", "config", cfg)
+
+	if len(args) > 0 && args[0] == "migrate" {
+		if err := utils.OpenDb(ctx, cfg.DB); err != nil {
+			fatal("Failed to connect to database", logging.Err(err))
+		}
+		defer utils.CloseDb()
+		if err := runMigrate(ctx, args[1:]); err != nil {
+			fatal("Migrate failed", logging.Err(err))
+		}
+		return
+	}
+
+	out, err := sink.New(ctx, cfg)
+	if err != nil {
+		fatal("Refusing to start", "sink", cfg.Sink.Type, logging.Err(err))
+	}
+	utils.SetTokenConfig(cfg)
+
+	// run returns instead of exiting so its deferred closes, and the sink's
+	// below, flush what is in flight before the process exits.
+	err = run(ctx, drainCtx, cfg, out, args)
+	if closeErr := out.Close(); closeErr != nil {
+		slog.Error("Failed to close sink", logging.Err(closeErr))
+	}
+	if err != nil {
+		fatal("Stopped", logging.Err(err))
+	}
+}
 
-	startSeq, err := utils.GetStartLedger()
+// run carries out the command in args, live ingestion when there is none.
+func run(ctx, drainCtx context.Context, cfg config.Config, out sink.Sink, args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "backfill":
+			if err := runBackfill(ctx, cfg, out, args[1:]); err != nil {
+				return fmt.Errorf("backfill failed: %w", err)
+			}
+			return nil
+		case "reprocess":
+			if err := runReprocess(ctx, cfg, out, args[1:]); err != nil {
+				return fmt.Errorf("reprocess failed: %w", err)
+			}
+			return nil
+		default:
+			return fmt.Errorf("unknown command %q: options (backfill, reprocess, migrate)", args[0])
+		}
+	}
+	return runLive(ctx, drainCtx, cfg, out)
+}
+
+// runLive follows the network from the live cursor until shutdown. It
+// returns an error only once an ingestion failure was followed by shutdown,
+// see haltIngestion.
+func runLive(ctx, drainCtx context.Context, cfg config.Config, out sink.Sink) error {
+	latestLedger := func(ctx context.Context) (uint32, error) {
+		return ledgersource.LatestLedger(ctx, cfg)
+	}
+	lastCommitted := func(ctx context.Context) (uint32, error) {
+		return out.Cursor(ctx, utils.INGEST_CURSOR_LIVE)
+	}
+	startSeq, err := utils.GetStartLedger(ctx, cfg.Environment, lastCommitted, latestLedger)
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("failed to determine start ledger: %w", err)
 	}
 
-	tx_handlers.InitReflectorAssets()
-	fmt.Println("Establishing the Indexer Connection ######## ", startSeq)
-	backend := ledgerbackend.NewRPCLedgerBackend(ledgerbackend.RPCLedgerBackendOptions{
-		RPCServerURL: config.RPC_URL,
-	})
+	initHandlers(ctx, cfg, out)
+	slog.Info("Establishing the indexer connection", logging.KeyLedger, startSeq)
+	backend, err := ledgersource.New(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create ledger source: %w", err)
+	}
 	defer backend.Close()
 	if err := backend.PrepareRange(ctx, ledgerbackend.UnboundedRange(startSeq)); err != nil {
-		log.Fatalf("Failed to prepare range: %v", err)
+		return fmt.Errorf("failed to prepare range from ledger %d: %w", startSeq, err)
+	}
+
+	registry, err := newRegistry(cfg.Processors)
+	if err != nil {
+		return fmt.Errorf("failed to enable processors: %w", err)
 	}
+	pool := newHandlerPool(cfg.Workers)
+	defer pool.Close()
+
+	server := startHTTPServer(cfg.HTTP, out.Ping)
+	defer shutdownServer(server, 5*time.Second)
+	go trackLatestLedger(ctx, 10*time.Second, latestLedger)
 
-	fmt.Println("CelarFi Indexer: Started.")
-	fmt.Println("Iterating over Stellar ledgers #########")
+	// Live ingestion outlasts source outages, retrying at MaxDelay for as
+	// long as they take; FETCH_MAX_ATTEMPTS only bounds backfill and
+	// reprocess.
+	retry := cfg.Source.Retry
+	retry.MaxAttempts = 0
+
+	slog.Info("CelarFi Indexer: Started, iterating over Stellar ledgers")
 	seq := startSeq
 	for {
-		ledger, err := backend.GetLedger(ctx, seq)
-		if err != nil {
-			fmt.Printf("No more ledgers or error at sequence %d: %v\n", seq, err)
-			// actually urgent error
+		ledger, ok, err := fetchLedger(ctx, drainCtx, out, backend, seq, retry, utils.INGEST_CURSOR_LIVE)
+		if ctx.Err() != nil {
+			slog.Info("Shutdown requested, stopped fetching ledgers", logging.KeyLedger, seq-1)
+			break
+		}
+		if errors.Is(err, ledgersource.ErrLedgerNotFound) {
+			slog.Info("No more ledgers in the ledger directory", logging.KeyLedger, seq-1)
 			break
 		}
-		tx_reader, err := ingest.NewLedgerTransactionReaderFromLedgerCloseMeta(network.PublicNetworkPassphrase, ledger)
 		if err != nil {
-			// send out the error
-
-			startSeq++
+			return haltIngestion(ctx, "Failed to get ledger", seq, err)
+		}
+		if !ok {
+			health.RecordCommit(seq)
+			seq++
 			continue
 		}
-		closeTime := ledger.LedgerHeaderHistoryEntry().Header.ScpValue.CloseTime
-		blockTime := time.Unix(int64(closeTime), 0).UTC()
-		_ = blockTime
 
-		transactionCount := ledger.CountTransactions()
-		fmt.Printf("Processing ledger %d with %d transactions...\n", seq, transactionCount)
-
-		for {
-			tx, readErr := tx_reader.Read()
-			if errors.Is(readErr, io.EOF) {
-				break
-			}
-			if readErr != nil {
-				log.Fatalf("error reading transaction: %v", readErr)
-			}
-			txResult := tx.Result.Result
-			tx_time := time.Unix(int64(tx_reader.GetHeader().Header.ScpValue.CloseTime), 0).UTC()
-			if txResult.Result.Code != xdr.TransactionResultCodeTxSuccess {
-				continue
+		ledgerStart := time.Now()
+		err = indexLedger(drainCtx, out, pool, registry, cfg.Network.Passphrase, utils.INGEST_CURSOR_LIVE, ledger)
+		if drainCtx.Err() != nil {
+			slog.Warn("Shutdown timeout reached, ledger will be reprocessed on restart", logging.KeyLedger, seq, logging.Err(err))
+			break
+		}
+		if err != nil {
+			if !errors.Is(err, sink.ErrLedgerAlreadyCommitted) {
+				return haltIngestion(ctx, "Failed to index ledger", seq, err)
 			}
-			for opIndex, op := range tx.Envelope.Operations() {
-				opResults := txResult.Result.Results
-				if opResults == nil || opIndex >= len(*opResults) {
-					continue
-				}
-
-				fmt.Printf("  - Found operation type: %s\n", op.Body.Type)
-				switch op.Body.Type {
-				case xdr.OperationTypeManageBuyOffer:
-					fmt.Println("    -> Handling ManageBuyOffer")
-					go tx_handlers.HandleManageBuyTransaction(tx, op, seq, opIndex, opResults, blockTime)
-				case xdr.OperationTypeManageSellOffer:
-					fmt.Println("    -> Handling ManageSellOffer")
-					go tx_handlers.HandleManageSellTransaction(tx, op, seq, opIndex, opResults, blockTime)
-				case xdr.OperationTypeLiquidityPoolDeposit:
-					// fmt.Println("found liquidity pool deposit")
-				case xdr.OperationTypeLiquidityPoolWithdraw:
-					// fmt.Println("found liquidity pool withdraw")
-				case xdr.OperationTypeInvokeHostFunction:
-					fmt.Println("    -> Handling InvokeHostFunction")
-					go tx_handlers.ProcessSorobanContracts(tx, seq, tx_time)
-				}
+			slog.Info("Skipping ledger", logging.KeyLedger, seq, logging.Err(err))
+		}
+		metrics.LedgerDuration.Observe(time.Since(ledgerStart).Seconds())
+		metrics.SetCurrentLedger(seq)
+		health.RecordCommit(seq)
+		if seq%100 == 0 {
+			printProcessorStats(registry)
+		}
 
-			}
+		seq++
+	}
+	slog.Info("CelarFi Indexer: Stopped", "last_ledger", seq-1)
+	return nil
+}
 
+// newRegistry enables the named processors, or all of them when names is
+// empty.
+func newRegistry(names []string) (*tx_handlers.Registry, error) {
+	var enabled []string
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			enabled = append(enabled, name)
 		}
+	}
+	registry, err := tx_handlers.NewRegistry(enabled)
+	if err != nil {
+		return nil, fmt.Errorf("%w: options (%s)", err, strings.Join(tx_handlers.ProcessorNames(), ", "))
+	}
+	return registry, nil
+}
 
-		seq++
+func printProcessorStats(registry *tx_handlers.Registry) {
+	stats := registry.Stats()
+	for _, processor := range registry.Processors() {
+		s := stats[processor.Name()]
+		slog.Info("Processor stats", logging.KeyProcessor, processor.Name(),
+			"transactions", s.Transactions, "rows", s.Rows, "errors", s.Errors, "busy", s.Duration)
 	}
 }
+
+// haltIngestion stops ingestion after an error it cannot recover from. The
+// process stays up with its HTTP server, /healthz reporting the failure,
+// until the operator or orchestrator shuts it down. The returned error then
+// makes the process exit non-zero.
+func haltIngestion(ctx context.Context, msg string, seq uint32, err error) error {
+	slog.Error(msg, logging.KeyLedger, seq, logging.Err(err))
+	health.RecordFailure(fmt.Errorf("ledger %d: %w", seq, err))
+	slog.Warn("Ingestion halted, waiting for shutdown", logging.KeyLedger, seq)
+	<-ctx.Done()
+	return fmt.Errorf("stopped after ingestion failure at ledger %d: %w", seq, err)
+}
+
+// fatal logs msg at error level and exits.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// initHandlers points the processors at the configured contracts and marks
+// the tokens out already holds as seen, so their details are not fetched
+// again. Failing to list them only costs those lookups.
+func initHandlers(ctx context.Context, cfg config.Config, out sink.Sink) {
+	tx_handlers.InitReflectorAssets(cfg.Network.Contracts.ReflectorOracles)
+	tx_handlers.SetAquariusContracts(cfg.Network.Contracts.Aquarius, cfg.Network.Contracts.AquariusRouter)
+	tokens, err := out.Tokens(ctx)
+	if err != nil {
+		slog.Warn("Failed to list stored tokens, their details will be fetched again", logging.Err(err))
+	}
+	tx_handlers.PreloadTokens(tokens)
+}
+
+func newHandlerPool(cfg config.WorkerConfig) *pipeline.Pool {
+	pool := pipeline.NewPool(cfg.Count, cfg.QueueSize)
+	metrics.RegisterQueueDepth(
+		func() float64 { return float64(pool.QueueDepth()) },
+		func() float64 { return float64(pool.InFlight()) },
+	)
+	return pool
+}
@@ -0,0 +1,171 @@
+package metrics
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "stellar_indexer"
+
+var registry = prometheus.NewRegistry()
+
+var (
+	CurrentLedger = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "current_ledger",
+		Help:      "Last ledger committed by live ingestion.",
+	})
+	LatestLedger = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "source_latest_ledger",
+		Help:      "Latest ledger reported by the ledger source (RPC node).",
+	})
+	LedgerLag = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "ledger_lag",
+		Help:      "Ledgers between the source's latest ledger and the last committed one.",
+	})
+	LedgerDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "ledger_processing_seconds",
+		Help:      "Time to process and commit one ledger.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 12),
+	})
+	LedgerFetchRetries = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ledger_fetch_retries_total",
+		Help:      "Ledger fetches retried after a transient error.",
+	})
+	DeadLetterLedgers = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "dead_letter_ledgers_total",
+		Help:      "Ledgers that could not be decoded and were recorded as dead letters.",
+	})
+	OperationsSeen = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "operations_seen_total",
+		Help:      "Operations read from ledgers by xdr.OperationType.",
+	}, []string{"type"})
+
+	RowsWritten = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rows_written_total",
+		Help:      "New rows written per table.",
+	}, []string{"table"})
+	DBInsertDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_insert_seconds",
+		Help:      "Latency of database writes per table.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"table"})
+	DBErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_errors_total",
+		Help:      "Failed database operations.",
+	}, []string{"operation"})
+
+	ExternalCallDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "external_call_seconds",
+		Help:      "Latency of RPC and Horizon calls made by the token and oracle helpers.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"service", "method"})
+	ExternalCallFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "external_call_failures_total",
+		Help:      "Failed RPC and Horizon calls made by the token and oracle helpers.",
+	}, []string{"service", "method"})
+
+	ProcessorTransactions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "processor_transactions_total",
+		Help:      "Transactions handed to each processor.",
+	}, []string{"processor"})
+	ProcessorRows = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "processor_rows_total",
+		Help:      "Rows derived by each processor.",
+	}, []string{"processor"})
+	ProcessorErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "processor_errors_total",
+		Help:      "Transactions each processor failed on.",
+	}, []string{"processor"})
+	ProcessorDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "processor_seconds",
+		Help:      "Time each processor spends on one transaction.",
+		Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 14),
+	}, []string{"processor"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		CurrentLedger, LatestLedger, LedgerLag, LedgerDuration,
+		LedgerFetchRetries, DeadLetterLedgers, OperationsSeen,
+		RowsWritten, DBInsertDuration, DBErrors,
+		ExternalCallDuration, ExternalCallFailures,
+		ProcessorTransactions, ProcessorRows, ProcessorErrors, ProcessorDuration,
+	)
+}
+
+// RegisterQueueDepth exposes the handler queue depth and in-flight task count
+// of the worker pool.
+func RegisterQueueDepth(queued, inFlight func() float64) {
+	registry.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "handler_queue_depth",
+			Help:      "Handler tasks waiting for a worker.",
+		}, queued),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "handler_tasks_in_flight",
+			Help:      "Handler tasks queued or running.",
+		}, inFlight),
+	)
+}
+
+// ObserveCall records the latency and outcome of an RPC or Horizon call.
+func ObserveCall(service, method string, start time.Time, err error) {
+	ExternalCallDuration.WithLabelValues(service, method).Observe(time.Since(start).Seconds())
+	if err != nil {
+		ExternalCallFailures.WithLabelValues(service, method).Inc()
+	}
+}
+
+var currentLedger, latestLedger atomic.Uint32
+
+// SetCurrentLedger records the last committed ledger and refreshes the lag.
+func SetCurrentLedger(seq uint32) {
+	currentLedger.Store(seq)
+	CurrentLedger.Set(float64(seq))
+	updateLag()
+}
+
+// SetLatestLedger records the source's latest ledger and refreshes the lag.
+func SetLatestLedger(seq uint32) {
+	latestLedger.Store(seq)
+	LatestLedger.Set(float64(seq))
+	updateLag()
+}
+
+func updateLag() {
+	current, latest := currentLedger.Load(), latestLedger.Load()
+	if current == 0 || latest == 0 {
+		return
+	}
+	LedgerLag.Set(float64(int64(latest) - int64(current)))
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
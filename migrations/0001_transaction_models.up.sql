@@ -0,0 +1,53 @@
+CREATE TABLE IF NOT EXISTS transaction_models (
+    id BIGSERIAL PRIMARY KEY,
+    block_time TIMESTAMPTZ NOT NULL,
+    ledger_sequence INTEGER NOT NULL,
+    transaction_hash TEXT NOT NULL,
+    operation_index INTEGER NOT NULL,
+    event_index INTEGER NOT NULL DEFAULT 0, -- position of the contract event for Soroban trades
+    dex_name TEXT,
+    source_account TEXT NOT NULL,
+    token_in TEXT,
+    token_out TEXT,
+    offer_id BIGINT,
+    dex_type TEXT,
+    pool_address TEXT,
+    matched_offer_id BIGINT, -- Nullable as it is optional
+    buyer_account TEXT,
+    seller_account TEXT,
+    
+    -- Using NUMERIC instead of DOUBLE PRECISION for financial accuracy
+    offer_buy_amount NUMERIC,
+    offer_sell_amount NUMERIC,
+    amount_bought NUMERIC,
+    amount_sold NUMERIC,
+    offer_price NUMERIC,
+    dex_fee NUMERIC,
+    
+    status TEXT,
+    
+    -- Storing the slice of structs as a JSON array
+    order_matches JSONB
+);
+
+-- Recommended Indexes for performance
+CREATE INDEX IF NOT EXISTS idx_tx_hash ON transaction_models(transaction_hash);
+CREATE INDEX IF NOT EXISTS idx_ledger_seq ON transaction_models(ledger_sequence);
+CREATE INDEX IF NOT EXISTS idx_source_account ON transaction_models(source_account);
+CREATE INDEX IF NOT EXISTS idx_pool_address ON transaction_models(pool_address);
+
+-- Natural key so a ledger can be reprocessed without duplicating trades.
+-- For tables created before event_index existed (from the old main.sql): add
+-- it, drop any duplicates, then key it.
+ALTER TABLE transaction_models ADD COLUMN IF NOT EXISTS event_index INTEGER NOT NULL DEFAULT 0;
+
+DELETE FROM transaction_models a
+USING transaction_models b
+WHERE a.id > b.id
+  AND a.ledger_sequence = b.ledger_sequence
+  AND a.transaction_hash = b.transaction_hash
+  AND a.operation_index = b.operation_index
+  AND a.event_index = b.event_index;
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_transaction_models_natural_key
+    ON transaction_models (ledger_sequence, transaction_hash, operation_index, event_index);
@@ -12,6 +12,6 @@ CREATE TABLE IF NOT EXISTS token_info (
     supply_breakdown JSONB
 );
 
-CREATE INDEX idx_token_symbol ON token_info(symbol);
-CREATE INDEX idx_token_name ON token_info(name);
-CREATE INDEX idx_token_is_sac ON token_info(is_sac);
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS idx_token_symbol ON token_info(symbol);
+CREATE INDEX IF NOT EXISTS idx_token_name ON token_info(name);
+CREATE INDEX IF NOT EXISTS idx_token_is_sac ON token_info(is_sac);
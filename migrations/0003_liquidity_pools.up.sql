@@ -3,9 +3,9 @@ CREATE TABLE IF NOT EXISTS liquidity_pools (
     token_a TEXT NOT NULL,
     token_b TEXT NOT NULL,
     fee_bps INTEGER, -- basis points...something like 30 for 0.3%
-    type TEXT, 
+    type TEXT,
     created_at TIMESTAMPTZ DEFAULT NOW()
 );
 
-CREATE INDEX idx_liquidity_pools_token_a ON liquidity_pools(token_a);
-CREATE INDEX idx_liquidity_pools_token_b ON liquidity_pools(token_b);
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS idx_liquidity_pools_token_a ON liquidity_pools(token_a);
+CREATE INDEX IF NOT EXISTS idx_liquidity_pools_token_b ON liquidity_pools(token_b);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS price_ticks;
+DROP TABLE IF EXISTS sources;
+DROP TABLE IF EXISTS assets;
@@ -0,0 +1,88 @@
+CREATE EXTENSION IF NOT EXISTS timescaledb;
+
+CREATE TABLE IF NOT EXISTS assets (
+    asset_id          TEXT        PRIMARY KEY,
+    asset_code        TEXT        NOT NULL,
+    asset_type        TEXT        NOT NULL CHECK (asset_type IN ('classic', 'soroban')),
+    issuer_address    TEXT,
+    contract_address  TEXT,
+    home_domain       TEXT,
+    asset_name        TEXT,
+    decimals          SMALLINT    NOT NULL DEFAULT 7,
+    is_active         BOOLEAN     NOT NULL DEFAULT TRUE,
+    first_seen_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS sources (
+    source_id        TEXT     PRIMARY KEY,
+    display_name     TEXT     NOT NULL,
+    source_type      TEXT     NOT NULL CHECK (source_type IN ('dex', 'amm', 'oracle_onchain', 'oracle_offchain', 'cex')),
+    reports_volume   BOOLEAN  NOT NULL DEFAULT FALSE,
+    is_enabled       BOOLEAN  NOT NULL DEFAULT TRUE,
+    health_status    TEXT     NOT NULL DEFAULT 'healthy' CHECK (health_status IN ('healthy', 'degraded', 'unavailable')),
+    last_tick_at     TIMESTAMPTZ,
+    config           JSONB
+);
+
+INSERT INTO sources (source_id, display_name, source_type, reports_volume) VALUES
+    ('reflector', 'Reflector Oracle', 'oracle_onchain',  FALSE),
+    ('soroswap',  'Soroswap',         'amm',             TRUE),
+    ('aquarius',  'Aquarius',         'amm',             TRUE),
+    ('sdex',      'Stellar DEX',      'dex',             TRUE),
+    ('redstone',  'Redstone',         'oracle_offchain', FALSE),
+    ('chainlink', 'Chainlink',        'oracle_offchain', FALSE),
+    ('seda',      'SEDA',             'oracle_offchain', FALSE)
+ON CONFLICT (source_id) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS price_ticks (
+    ts            TIMESTAMPTZ     NOT NULL,
+    asset_id      TEXT            NOT NULL REFERENCES assets(asset_id),
+    source_id     TEXT            NOT NULL REFERENCES sources(source_id),
+    source_type   TEXT            NOT NULL,
+    price_usd     NUMERIC(28, 12) NOT NULL,
+    volume_usd    NUMERIC(28, 12),
+    base_volume   NUMERIC(28, 12),
+    quote_volume  NUMERIC(28, 12),
+    ledger_seq    BIGINT,
+    tx_hash       TEXT,
+    raw_payload   JSONB,
+    ingested_at   TIMESTAMPTZ     NOT NULL DEFAULT NOW()
+);
+
+SELECT create_hypertable('price_ticks', 'ts', if_not_exists => TRUE);
+
+-- Compression settings cannot be changed once chunks are compressed, so only
+-- set them when adopting a table that does not have them yet.
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM timescaledb_information.hypertables
+        WHERE hypertable_name = 'price_ticks' AND compression_enabled
+    ) THEN
+        ALTER TABLE price_ticks SET (
+            timescaledb.compress,
+            timescaledb.compress_segmentby = 'asset_id, source_id',
+            timescaledb.compress_orderby   = 'ts DESC'
+        );
+    END IF;
+END $$;
+
+SELECT add_compression_policy('price_ticks', INTERVAL '7 days', if_not_exists => TRUE);
+SELECT add_retention_policy('price_ticks', INTERVAL '90 days', if_not_exists => TRUE);
+
+CREATE INDEX IF NOT EXISTS price_ticks_asset_ts_idx  ON price_ticks (asset_id, ts DESC);
+CREATE INDEX IF NOT EXISTS price_ticks_source_ts_idx ON price_ticks (source_id, ts DESC);
+-- Natural key so replayed ledgers do not duplicate ticks; includes ts as required by the hypertable.
+-- Tables created before the key existed may hold duplicates: keep one of each
+-- first. Duplicates share ts and so sit in the same chunk, where ctid orders
+-- them.
+DELETE FROM price_ticks a
+USING price_ticks b
+WHERE a.ctid > b.ctid
+  AND a.asset_id = b.asset_id
+  AND a.source_id = b.source_id
+  AND a.ts = b.ts
+  AND a.tx_hash = b.tx_hash;
+
+CREATE UNIQUE INDEX IF NOT EXISTS price_ticks_natural_key ON price_ticks (asset_id, source_id, ts, tx_hash);
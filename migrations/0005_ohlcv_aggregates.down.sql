@@ -0,0 +1,6 @@
+-- migrate: no-transaction
+
+DROP MATERIALIZED VIEW IF EXISTS ohlcv_1day;
+DROP MATERIALIZED VIEW IF EXISTS ohlcv_1hour;
+DROP MATERIALIZED VIEW IF EXISTS ohlcv_1min;
+DROP MATERIALIZED VIEW IF EXISTS ohlcv_1min_by_source;
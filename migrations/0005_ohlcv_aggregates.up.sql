@@ -1,68 +1,5 @@
-CREATE EXTENSION IF NOT EXISTS timescaledb;
-
-CREATE TABLE IF NOT EXISTS assets (
-    asset_id          TEXT        PRIMARY KEY,
-    asset_code        TEXT        NOT NULL,
-    asset_type        TEXT        NOT NULL CHECK (asset_type IN ('classic', 'soroban')),
-    issuer_address    TEXT,
-    contract_address  TEXT,
-    home_domain       TEXT,
-    asset_name        TEXT,
-    decimals          SMALLINT    NOT NULL DEFAULT 7,
-    is_active         BOOLEAN     NOT NULL DEFAULT TRUE,
-    first_seen_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-    updated_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
-);
-
-CREATE TABLE IF NOT EXISTS sources (
-    source_id        TEXT     PRIMARY KEY,
-    display_name     TEXT     NOT NULL,
-    source_type      TEXT     NOT NULL CHECK (source_type IN ('dex', 'amm', 'oracle_onchain', 'oracle_offchain', 'cex')),
-    reports_volume   BOOLEAN  NOT NULL DEFAULT FALSE,
-    is_enabled       BOOLEAN  NOT NULL DEFAULT TRUE,
-    health_status    TEXT     NOT NULL DEFAULT 'healthy' CHECK (health_status IN ('healthy', 'degraded', 'unavailable')),
-    last_tick_at     TIMESTAMPTZ,
-    config           JSONB
-);
-
-INSERT INTO sources (source_id, display_name, source_type, reports_volume) VALUES
-    ('reflector', 'Reflector Oracle', 'oracle_onchain',  FALSE),
-    ('soroswap',  'Soroswap',         'amm',             TRUE),
-    ('aquarius',  'Aquarius',         'amm',             TRUE),
-    ('sdex',      'Stellar DEX',      'dex',             TRUE),
-    ('redstone',  'Redstone',         'oracle_offchain', FALSE),
-    ('chainlink', 'Chainlink',        'oracle_offchain', FALSE),
-    ('seda',      'SEDA',             'oracle_offchain', FALSE)
-ON CONFLICT (source_id) DO NOTHING;
-
-CREATE TABLE IF NOT EXISTS price_ticks (
-    ts            TIMESTAMPTZ     NOT NULL,
-    asset_id      TEXT            NOT NULL REFERENCES assets(asset_id),
-    source_id     TEXT            NOT NULL REFERENCES sources(source_id),
-    source_type   TEXT            NOT NULL,
-    price_usd     NUMERIC(28, 12) NOT NULL,
-    volume_usd    NUMERIC(28, 12),
-    base_volume   NUMERIC(28, 12),
-    quote_volume  NUMERIC(28, 12),
-    ledger_seq    BIGINT,
-    tx_hash       TEXT,
-    raw_payload   JSONB,
-    ingested_at   TIMESTAMPTZ     NOT NULL DEFAULT NOW()
-);
-
-SELECT create_hypertable('price_ticks', 'ts', if_not_exists => TRUE);
-
-ALTER TABLE price_ticks SET (
-    timescaledb.compress,
-    timescaledb.compress_segmentby = 'asset_id, source_id',
-    timescaledb.compress_orderby   = 'ts DESC'
-);
-
-SELECT add_compression_policy('price_ticks', INTERVAL '7 days', if_not_exists => TRUE);
-SELECT add_retention_policy('price_ticks', INTERVAL '90 days', if_not_exists => TRUE);
-
-CREATE INDEX IF NOT EXISTS price_ticks_asset_ts_idx  ON price_ticks (asset_id, ts DESC);
-CREATE INDEX IF NOT EXISTS price_ticks_source_ts_idx ON price_ticks (source_id, ts DESC);
+-- migrate: no-transaction
+-- Continuous aggregates cannot be created inside a transaction block.
 
 -- 1-minute per source
 CREATE MATERIALIZED VIEW IF NOT EXISTS ohlcv_1min_by_source
@@ -171,4 +108,4 @@ SELECT add_continuous_aggregate_policy('ohlcv_1day',
     end_offset        => INTERVAL '2 days',
     schedule_interval => INTERVAL '1 day',
     if_not_exists     => TRUE
-);
\ No newline at end of file
+);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS b2b_request_logs;
+DROP TYPE IF EXISTS service_type;
+DROP TYPE IF EXISTS available_chains;
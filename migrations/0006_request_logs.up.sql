@@ -1,5 +1,12 @@
-CREATE TYPE available_chains AS ENUM ('stellar');
-CREATE TYPE service_type AS ENUM ('indexer', 'rpc');
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'available_chains') THEN
+        CREATE TYPE available_chains AS ENUM ('stellar');
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'service_type') THEN
+        CREATE TYPE service_type AS ENUM ('indexer', 'rpc');
+    END IF;
+END $$;
 
 CREATE TABLE IF NOT EXISTS b2b_request_logs (
     log_id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
@@ -23,7 +30,7 @@ CREATE TABLE IF NOT EXISTS b2b_request_logs (
     correlation_id   UUID
 );
 
-CREATE INDEX idx_logs_project_time ON b2b_request_logs (project_id, received_at DESC);
-CREATE INDEX idx_logs_service_status_time ON b2b_request_logs (service_id, status, received_at DESC);
-CREATE INDEX idx_logs_source_ip ON b2b_request_logs (source_ip);
-CREATE INDEX idx_logs_api_key ON b2b_request_logs (api_key_id);
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS idx_logs_project_time ON b2b_request_logs (project_id, received_at DESC);
+CREATE INDEX IF NOT EXISTS idx_logs_service_status_time ON b2b_request_logs (service_id, status, received_at DESC);
+CREATE INDEX IF NOT EXISTS idx_logs_source_ip ON b2b_request_logs (source_ip);
+CREATE INDEX IF NOT EXISTS idx_logs_api_key ON b2b_request_logs (api_key_id);
@@ -0,0 +1,10 @@
+-- Durable ingestion cursor. ledger_sequence is the last ledger whose derived
+-- rows were fully committed; it is advanced in the same transaction as them.
+CREATE TABLE IF NOT EXISTS ingest_state (
+    cursor_name     TEXT PRIMARY KEY,
+    ledger_sequence BIGINT NOT NULL,
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Live ingestion uses the 'live' cursor. Each backfill chunk keeps its own
+-- 'backfill:<from>-<to>' cursor; a chunk is complete once it reaches <to>.
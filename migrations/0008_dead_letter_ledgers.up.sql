@@ -0,0 +1,13 @@
+-- Ledgers ingestion could not decode. The cursor named in cursor_name moved
+-- past them; raw_meta keeps the LedgerCloseMeta XDR so they can be inspected
+-- and reprocessed once the cause is fixed.
+CREATE TABLE IF NOT EXISTS dead_letter_ledgers (
+    id              BIGSERIAL PRIMARY KEY,
+    ledger_sequence BIGINT NOT NULL,
+    cursor_name     TEXT,
+    error           TEXT NOT NULL,
+    raw_meta        BYTEA,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_dead_letter_ledgers_seq ON dead_letter_ledgers (ledger_sequence);
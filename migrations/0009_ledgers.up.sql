@@ -0,0 +1,26 @@
+-- One row per processed ledger, written in the same transaction as the rows
+-- derived from it. Consecutive rows must chain through previous_ledger_hash,
+-- and a missing sequence is a ledger that was never committed.
+CREATE TABLE IF NOT EXISTS ledgers (
+    ledger_sequence                     BIGINT PRIMARY KEY,
+    ledger_hash                         TEXT NOT NULL,
+    previous_ledger_hash                TEXT NOT NULL,
+    closed_at                           TIMESTAMPTZ NOT NULL,
+    protocol_version                    INTEGER NOT NULL,
+    base_fee                            BIGINT NOT NULL,
+    base_reserve                        BIGINT NOT NULL,
+    total_coins                         BIGINT NOT NULL,
+    fee_pool                            BIGINT NOT NULL,
+    successful_transaction_count        INTEGER NOT NULL,
+    failed_transaction_count            INTEGER NOT NULL,
+    successful_operation_count          INTEGER NOT NULL,
+    failed_operation_count              INTEGER NOT NULL,
+    fee_charged                         BIGINT NOT NULL,
+    soroban_non_refundable_fee_charged  BIGINT NOT NULL,
+    soroban_refundable_fee_charged      BIGINT NOT NULL,
+    soroban_rent_fee_charged            BIGINT NOT NULL,
+    ingested_at                         TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ledgers_hash ON ledgers (ledger_hash);
+CREATE INDEX IF NOT EXISTS idx_ledgers_closed_at ON ledgers (closed_at);
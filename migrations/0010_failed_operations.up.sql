@@ -0,0 +1,21 @@
+-- Every operation of a failed transaction with its decoded result code.
+-- result_code is the transaction's code when the transaction failed before
+-- its operations were applied. fee_charged is the transaction's fee.
+CREATE TABLE IF NOT EXISTS failed_operations (
+    block_time              TIMESTAMPTZ NOT NULL,
+    ledger_sequence         BIGINT NOT NULL,
+    transaction_hash        TEXT NOT NULL,
+    operation_index         INTEGER NOT NULL,
+    operation_type          TEXT NOT NULL,
+    source_account          TEXT NOT NULL,
+    transaction_result_code TEXT NOT NULL,
+    result_code             TEXT NOT NULL,
+    fee_charged             BIGINT NOT NULL,
+    contract_id             TEXT,
+    function_name           TEXT,
+    PRIMARY KEY (ledger_sequence, transaction_hash, operation_index)
+);
+
+CREATE INDEX IF NOT EXISTS idx_failed_operations_type_code ON failed_operations (operation_type, result_code);
+CREATE INDEX IF NOT EXISTS idx_failed_operations_source ON failed_operations (source_account);
+CREATE INDEX IF NOT EXISTS idx_failed_operations_contract ON failed_operations (contract_id) WHERE contract_id IS NOT NULL;
@@ -0,0 +1,26 @@
+-- One row per fill (ClaimAtom) of an operation that crossed the order book.
+-- base_asset / quote_asset are in canonical asset order and price is quote
+-- per base, so volume and VWAP per market are plain aggregates.
+-- base_is_seller is true when the maker sold the base asset.
+CREATE TABLE IF NOT EXISTS trades (
+    block_time       TIMESTAMPTZ NOT NULL,
+    ledger_sequence  BIGINT NOT NULL,
+    transaction_hash TEXT NOT NULL,
+    operation_index  INTEGER NOT NULL,
+    claim_index      INTEGER NOT NULL,
+    operation_type   TEXT NOT NULL,
+    taker_account    TEXT NOT NULL,
+    maker_account    TEXT NOT NULL,
+    maker_offer_id   BIGINT NOT NULL,
+    base_asset       TEXT NOT NULL,
+    quote_asset      TEXT NOT NULL,
+    base_amount      NUMERIC NOT NULL,
+    quote_amount     NUMERIC NOT NULL,
+    price            NUMERIC NOT NULL,
+    base_is_seller   BOOLEAN NOT NULL,
+    PRIMARY KEY (ledger_sequence, transaction_hash, operation_index, claim_index)
+);
+
+CREATE INDEX IF NOT EXISTS idx_trades_pair_time ON trades (base_asset, quote_asset, block_time DESC);
+CREATE INDEX IF NOT EXISTS idx_trades_maker ON trades (maker_account, block_time DESC);
+CREATE INDEX IF NOT EXISTS idx_trades_taker ON trades (taker_account, block_time DESC);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_trades_liquidity_pool;
+ALTER TABLE trades DROP COLUMN IF EXISTS liquidity_pool_id;
+DROP TABLE IF EXISTS path_payments;
@@ -0,0 +1,26 @@
+-- One row per successful path payment, end to end. The fills it made along
+-- the way are in trades under the same operation.
+CREATE TABLE IF NOT EXISTS path_payments (
+    block_time          TIMESTAMPTZ NOT NULL,
+    ledger_sequence     BIGINT NOT NULL,
+    transaction_hash    TEXT NOT NULL,
+    operation_index     INTEGER NOT NULL,
+    operation_type      TEXT NOT NULL,
+    source_account      TEXT NOT NULL,
+    destination_account TEXT NOT NULL,
+    source_asset        TEXT NOT NULL,
+    destination_asset   TEXT NOT NULL,
+    path                TEXT[] NOT NULL DEFAULT '{}',
+    source_amount       NUMERIC NOT NULL,
+    destination_amount  NUMERIC NOT NULL,
+    PRIMARY KEY (ledger_sequence, transaction_hash, operation_index)
+);
+
+CREATE INDEX IF NOT EXISTS idx_path_payments_source ON path_payments (source_account, block_time DESC);
+CREATE INDEX IF NOT EXISTS idx_path_payments_destination ON path_payments (destination_account, block_time DESC);
+CREATE INDEX IF NOT EXISTS idx_path_payments_assets ON path_payments (source_asset, destination_asset, block_time DESC);
+
+-- Fills against a liquidity pool have the pool as counterparty: maker_account
+-- holds the pool ID and maker_offer_id is 0.
+ALTER TABLE trades ADD COLUMN IF NOT EXISTS liquidity_pool_id TEXT;
+CREATE INDEX IF NOT EXISTS idx_trades_liquidity_pool ON trades (liquidity_pool_id, block_time DESC) WHERE liquidity_pool_id IS NOT NULL;
@@ -0,0 +1 @@
+ALTER TABLE transaction_models DROP COLUMN IF EXISTS passive;
@@ -0,0 +1,3 @@
+-- Offers placed with CreatePassiveSellOffer, which never take an offer at
+-- exactly their own price.
+ALTER TABLE transaction_models ADD COLUMN IF NOT EXISTS passive BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,25 @@
+-- Latest state of every order book offer seen, kept from offer ledger entry
+-- changes. state is open, or terminal: cancelled (deleted by its owner with
+-- amount 0), consumed (filled to the end) or removed (taken off the book for
+-- another reason, such as a revoked trustline). created_ledger is NULL for
+-- offers created before indexing started.
+CREATE TABLE IF NOT EXISTS offers (
+    offer_id              BIGINT PRIMARY KEY,
+    seller_account        TEXT NOT NULL,
+    selling_asset         TEXT NOT NULL,
+    buying_asset          TEXT NOT NULL,
+    amount                NUMERIC NOT NULL,
+    price_n               INTEGER NOT NULL,
+    price_d               INTEGER NOT NULL,
+    price                 NUMERIC NOT NULL,
+    flags                 INTEGER NOT NULL,
+    created_ledger        BIGINT,
+    last_modified_ledger  BIGINT NOT NULL,
+    state                 TEXT NOT NULL,
+    last_transaction_hash TEXT NOT NULL,
+    updated_at            TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_offers_seller ON offers (seller_account, state);
+CREATE INDEX IF NOT EXISTS idx_offers_pair_open ON offers (selling_asset, buying_asset, price) WHERE state = 'open';
+CREATE INDEX IF NOT EXISTS idx_offers_last_modified ON offers (last_modified_ledger);
@@ -0,0 +1,23 @@
+-- One row per successful deposit into or withdrawal from a classic liquidity
+-- pool. Swaps against a pool are in trades with the pool as counterparty.
+CREATE TABLE IF NOT EXISTS liquidity_pool_operations (
+    block_time        TIMESTAMPTZ NOT NULL,
+    ledger_sequence   BIGINT NOT NULL,
+    transaction_hash  TEXT NOT NULL,
+    operation_index   INTEGER NOT NULL,
+    operation_type    TEXT NOT NULL,
+    liquidity_pool_id TEXT NOT NULL,
+    account           TEXT NOT NULL,
+    asset_a           TEXT NOT NULL,
+    asset_b           TEXT NOT NULL,
+    amount_a          NUMERIC NOT NULL, -- into the pool on deposit, out of it on withdraw
+    amount_b          NUMERIC NOT NULL,
+    shares            NUMERIC NOT NULL, -- minted on deposit, burned on withdraw
+    reserve_a         NUMERIC NOT NULL, -- pool reserves and shares after the operation
+    reserve_b         NUMERIC NOT NULL,
+    total_shares      NUMERIC NOT NULL,
+    PRIMARY KEY (ledger_sequence, transaction_hash, operation_index)
+);
+
+CREATE INDEX IF NOT EXISTS idx_liquidity_pool_operations_pool ON liquidity_pool_operations (liquidity_pool_id, block_time DESC);
+CREATE INDEX IF NOT EXISTS idx_liquidity_pool_operations_account ON liquidity_pool_operations (account, block_time DESC);
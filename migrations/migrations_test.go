@@ -0,0 +1,108 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// testDB connects to the TimescaleDB database named by TEST_DATABASE_URL
+// with a fresh schema first on the search path, dropped when the test ends.
+func testDB(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("set TEST_DATABASE_URL to a TimescaleDB database to run migration tests")
+	}
+	ctx := context.Background()
+	schema := fmt.Sprintf("migrations_test_%d", time.Now().UnixNano())
+
+	cfg, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.ConnConfig.RuntimeParams["search_path"] = schema + ",public"
+	db, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The extension must already be installed, or CREATE EXTENSION would put
+	// it in the test schema and the cleanup would drop it.
+	var installed bool
+	if err := db.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'timescaledb')").Scan(&installed); err != nil || !installed {
+		db.Close()
+		t.Skipf("timescaledb is not installed in TEST_DATABASE_URL (err %v)", err)
+	}
+	if _, err := db.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+		db.Close()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Exec(context.Background(), "DROP SCHEMA "+schema+" CASCADE")
+		db.Close()
+	})
+	return db
+}
+
+func migration(t *testing.T, version int) Migration {
+	t.Helper()
+	all, err := All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range all {
+		if m.Version == version {
+			return m
+		}
+	}
+	t.Fatalf("no migration %04d", version)
+	return Migration{}
+}
+
+// TestPriceTicksDropsDuplicates applies the price_ticks migration to a table
+// created before its natural key existed and holding duplicate ticks.
+func TestPriceTicksDropsDuplicates(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	priceTicks := migration(t, 4)
+
+	if _, err := db.Exec(ctx, priceTicks.Up); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(ctx, "DROP INDEX price_ticks_natural_key"); err != nil {
+		t.Fatal(err)
+	}
+	_, err := db.Exec(ctx, `
+		INSERT INTO assets (asset_id, asset_code, asset_type) VALUES ('XLM', 'XLM', 'classic');
+		INSERT INTO price_ticks (ts, asset_id, source_id, source_type, price_usd, ledger_seq, tx_hash) VALUES
+			('2025-01-01 00:00:00+00', 'XLM', 'reflector', 'oracle_onchain', 0.10, 1, 'a'),
+			('2025-01-01 00:00:00+00', 'XLM', 'reflector', 'oracle_onchain', 0.10, 1, 'a'),
+			('2025-01-01 00:00:00+00', 'XLM', 'reflector', 'oracle_onchain', 0.10, 1, 'a'),
+			('2025-01-01 00:00:00+00', 'XLM', 'reflector', 'oracle_onchain', 0.11, 1, 'b'),
+			('2025-01-01 00:00:05+00', 'XLM', 'reflector', 'oracle_onchain', 0.12, 2, 'a'),
+			('2025-01-01 00:00:05+00', 'XLM', 'reflector', 'oracle_onchain', 0.12, 2, 'a')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.Exec(ctx, priceTicks.Up); err != nil {
+		t.Fatalf("rerunning migration over duplicates: %v", err)
+	}
+	var count int
+	if err := db.QueryRow(ctx, "SELECT count(*) FROM price_ticks").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("got %d ticks, want 3", count)
+	}
+	_, err = db.Exec(ctx, `INSERT INTO price_ticks (ts, asset_id, source_id, source_type, price_usd, ledger_seq, tx_hash)
+		VALUES ('2025-01-01 00:00:00+00', 'XLM', 'reflector', 'oracle_onchain', 0.10, 1, 'a')`)
+	if err == nil || !strings.Contains(err.Error(), "price_ticks_natural_key") {
+		t.Errorf("duplicate insert err = %v, want a price_ticks_natural_key violation", err)
+	}
+}
@@ -3,28 +3,30 @@ package models
 import "time"
 
 type TransactionModels struct {
-	BlockTime       time.Time
-	LedgerSequence  uint32
-	TransactionHash string
-	OperationIndex  int
-	DexName         string
-	SourceAccount   string // signer/source of the offer (instead of "Signature")
-	TokenIn         string
-	TokenOut        string
-	OfferID         uint64
-	Dex_type        string
-	PoolAddress     string
-	MatchedOfferID  uint64 // (optional; if specific counteroffer was matched)
-	BuyerAccount    string
-	SellerAccount   string
-	OfferBuyAmount  float64
-	OfferSellAmount float64
-	AmountBought    float64
-	AmountSold      float64
-	OfferPrice      float64
-	DexFee          float64
-	Status          string
-	OrderMatches    []OrderMatch // plural should be singular in struct definition
+	BlockTime       time.Time    `json:"block_time"`
+	LedgerSequence  uint32       `json:"ledger_sequence"`
+	TransactionHash string       `json:"transaction_hash"`
+	OperationIndex  int          `json:"operation_index"`
+	EventIndex      int          `json:"event_index"` // index of the contract event within the transaction; 0 for classic operations
+	DexName         string       `json:"dex_name"`
+	SourceAccount   string       `json:"source_account"` // signer/source of the offer (instead of "Signature")
+	TokenIn         string       `json:"token_in"`
+	TokenOut        string       `json:"token_out"`
+	OfferID         uint64       `json:"offer_id"`
+	Dex_type        string       `json:"dex_type"`
+	PoolAddress     string       `json:"pool_address"`
+	MatchedOfferID  uint64       `json:"matched_offer_id"` // (optional; if specific counteroffer was matched)
+	BuyerAccount    string       `json:"buyer_account"`
+	SellerAccount   string       `json:"seller_account"`
+	OfferBuyAmount  float64      `json:"offer_buy_amount"`
+	OfferSellAmount float64      `json:"offer_sell_amount"`
+	AmountBought    float64      `json:"amount_bought"`
+	AmountSold      float64      `json:"amount_sold"`
+	OfferPrice      float64      `json:"offer_price"`
+	DexFee          float64      `json:"dex_fee"`
+	Status          string       `json:"status"`
+	Passive         bool         `json:"passive"`       // created by CreatePassiveSellOffer
+	OrderMatches    []OrderMatch `json:"order_matches"` // plural should be singular in struct definition
 }
 
 type OrderMatch struct {
@@ -53,10 +55,10 @@ type Transfers struct {
 }
 
 type LiquidityPool struct {
-	PoolAddress string    
-	TokenA      string    
-	TokenB      string    
-	FeeBps      int32     
-	Type        string    
-	CreatedAt   time.Time 
+	PoolAddress string    `json:"pool_address"`
+	TokenA      string    `json:"token_a"`
+	TokenB      string    `json:"token_b"`
+	FeeBps      int32     `json:"fee_bps"`
+	Type        string    `json:"type"`
+	CreatedAt   time.Time `json:"created_at"`
 }
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// FailedOperation is an operation of a transaction that did not succeed.
+// ResultCode is the operation's own result code, or the transaction's when
+// the transaction failed before its operations were applied.
+type FailedOperation struct {
+	BlockTime             time.Time `json:"block_time"`
+	LedgerSequence        uint32    `json:"ledger_sequence"`
+	TransactionHash       string    `json:"transaction_hash"`
+	OperationIndex        int       `json:"operation_index"`
+	OperationType         string    `json:"operation_type"`
+	SourceAccount         string    `json:"source_account"`
+	TransactionResultCode string    `json:"transaction_result_code"`
+	ResultCode            string    `json:"result_code"`
+	FeeCharged            int64     `json:"fee_charged"`   // stroops, charged once per transaction
+	ContractID            string    `json:"contract_id"`   // Soroban invocations only
+	FunctionName          string    `json:"function_name"` // Soroban invocations only
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Ledger is the header of a processed ledger together with totals over its
+// transactions. One row is stored per ledger, whatever processors are enabled.
+type Ledger struct {
+	Sequence                       uint32    `json:"ledger_sequence"`
+	Hash                           string    `json:"ledger_hash"`
+	PreviousHash                   string    `json:"previous_ledger_hash"`
+	ClosedAt                       time.Time `json:"closed_at"`
+	ProtocolVersion                uint32    `json:"protocol_version"`
+	BaseFee                        uint32    `json:"base_fee"`     // stroops
+	BaseReserve                    uint32    `json:"base_reserve"` // stroops
+	TotalCoins                     int64     `json:"total_coins"`  // stroops
+	FeePool                        int64     `json:"fee_pool"`     // stroops
+	SuccessfulTransactionCount     int       `json:"successful_transaction_count"`
+	FailedTransactionCount         int       `json:"failed_transaction_count"`
+	SuccessfulOperationCount       int       `json:"successful_operation_count"` // operations in successful transactions
+	FailedOperationCount           int       `json:"failed_operation_count"`     // operations in failed transactions
+	FeeCharged                     int64     `json:"fee_charged"`
+	SorobanNonRefundableFeeCharged int64     `json:"soroban_non_refundable_fee_charged"`
+	SorobanRefundableFeeCharged    int64     `json:"soroban_refundable_fee_charged"`
+	SorobanRentFeeCharged          int64     `json:"soroban_rent_fee_charged"`
+}
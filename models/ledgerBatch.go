@@ -0,0 +1,97 @@
+package models
+
+// LedgerBatch holds every row derived from a single ledger. It is committed
+// in one database transaction together with the ingestion cursor.
+type LedgerBatch struct {
+	LedgerSequence   uint32                   `json:"-"`
+	Ledger           *Ledger                  `json:"ledger"`
+	Transactions     []TransactionModels      `json:"transactions"`
+	PriceTicks       []PriceTick              `json:"price_ticks"`
+	Trades           []Trade                  `json:"trades"`
+	PathPayments     []PathPayment            `json:"path_payments"`
+	PoolOperations   []LiquidityPoolOperation `json:"liquidity_pool_operations"`
+	Offers           []Offer                  `json:"offers"`
+	FailedOperations []FailedOperation        `json:"failed_operations"`
+	Tokens           []TokenInfo              `json:"tokens,omitempty"`
+	Pools            []LiquidityPool          `json:"pools,omitempty"`
+}
+
+// Add sorts processor rows into the typed slices of the batch.
+func (b *LedgerBatch) Add(rows ...Row) {
+	for _, row := range rows {
+		switch r := row.(type) {
+		case TransactionModels:
+			b.Transactions = append(b.Transactions, r)
+		case PriceTick:
+			b.PriceTicks = append(b.PriceTicks, r)
+		case Trade:
+			b.Trades = append(b.Trades, r)
+		case PathPayment:
+			b.PathPayments = append(b.PathPayments, r)
+		case LiquidityPoolOperation:
+			b.PoolOperations = append(b.PoolOperations, r)
+		case Offer:
+			b.Offers = append(b.Offers, r)
+		case FailedOperation:
+			b.FailedOperations = append(b.FailedOperations, r)
+		case TokenInfo:
+			b.Tokens = append(b.Tokens, r)
+		case LiquidityPool:
+			b.Pools = append(b.Pools, r)
+		case Ledger:
+			b.Ledger = &r
+		}
+	}
+}
+
+// Append merges another batch of the same ledger into b.
+func (b *LedgerBatch) Append(other LedgerBatch) {
+	if other.Ledger != nil {
+		b.Ledger = other.Ledger
+	}
+	b.Transactions = append(b.Transactions, other.Transactions...)
+	b.PriceTicks = append(b.PriceTicks, other.PriceTicks...)
+	b.Trades = append(b.Trades, other.Trades...)
+	b.PathPayments = append(b.PathPayments, other.PathPayments...)
+	b.PoolOperations = append(b.PoolOperations, other.PoolOperations...)
+	b.Offers = append(b.Offers, other.Offers...)
+	b.FailedOperations = append(b.FailedOperations, other.FailedOperations...)
+	b.Tokens = append(b.Tokens, other.Tokens...)
+	b.Pools = append(b.Pools, other.Pools...)
+}
+
+// Rows lists every row of the batch, the ledger's own row first.
+func (b LedgerBatch) Rows() []Row {
+	var rows []Row
+	if b.Ledger != nil {
+		rows = append(rows, *b.Ledger)
+	}
+	for _, r := range b.Transactions {
+		rows = append(rows, r)
+	}
+	for _, r := range b.PriceTicks {
+		rows = append(rows, r)
+	}
+	for _, r := range b.Trades {
+		rows = append(rows, r)
+	}
+	for _, r := range b.PathPayments {
+		rows = append(rows, r)
+	}
+	for _, r := range b.PoolOperations {
+		rows = append(rows, r)
+	}
+	for _, r := range b.Offers {
+		rows = append(rows, r)
+	}
+	for _, r := range b.FailedOperations {
+		rows = append(rows, r)
+	}
+	for _, r := range b.Tokens {
+		rows = append(rows, r)
+	}
+	for _, r := range b.Pools {
+		rows = append(rows, r)
+	}
+	return rows
+}
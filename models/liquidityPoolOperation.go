@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// LiquidityPoolOperation is a deposit into or a withdrawal from a classic
+// liquidity pool. AmountA and AmountB are what moved in or out of each
+// reserve and Shares the pool shares minted or burned. The reserves and
+// total shares are the pool's after the operation.
+type LiquidityPoolOperation struct {
+	BlockTime       time.Time `json:"block_time"`
+	LedgerSequence  uint32    `json:"ledger_sequence"`
+	TransactionHash string    `json:"transaction_hash"`
+	OperationIndex  int       `json:"operation_index"`
+	OperationType   string    `json:"operation_type"`
+	LiquidityPoolID string    `json:"liquidity_pool_id"`
+	Account         string    `json:"account"`
+	AssetA          string    `json:"asset_a"`
+	AssetB          string    `json:"asset_b"`
+	AmountA         float64   `json:"amount_a"`
+	AmountB         float64   `json:"amount_b"`
+	Shares          float64   `json:"shares"`
+	ReserveA        float64   `json:"reserve_a"`
+	ReserveB        float64   `json:"reserve_b"`
+	TotalShares     float64   `json:"total_shares"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Offer is the latest known state of an order book offer, kept from the
+// offer's ledger entry changes. Amount is what is left to sell, 0 once the
+// offer reached a terminal State. CreatedLedger is 0 when the offer was
+// created before indexing started.
+type Offer struct {
+	OfferID             uint64    `json:"offer_id"`
+	SellerAccount       string    `json:"seller_account"`
+	SellingAsset        string    `json:"selling_asset"`
+	BuyingAsset         string    `json:"buying_asset"`
+	Amount              float64   `json:"amount"`
+	PriceN              int32     `json:"price_n"`
+	PriceD              int32     `json:"price_d"`
+	Price               float64   `json:"price"` // buying per selling
+	Flags               uint32    `json:"flags"`
+	CreatedLedger       uint32    `json:"created_ledger"`
+	LastModifiedLedger  uint32    `json:"last_modified_ledger"`
+	State               string    `json:"state"`
+	LastTransactionHash string    `json:"last_transaction_hash"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
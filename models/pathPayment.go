@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// PathPayment is a path payment end to end: what left the source account and
+// what arrived at the destination, through the assets of Path in order. The
+// individual fills along the way are Trades of the same operation.
+type PathPayment struct {
+	BlockTime          time.Time `json:"block_time"`
+	LedgerSequence     uint32    `json:"ledger_sequence"`
+	TransactionHash    string    `json:"transaction_hash"`
+	OperationIndex     int       `json:"operation_index"`
+	OperationType      string    `json:"operation_type"`
+	SourceAccount      string    `json:"source_account"`
+	DestinationAccount string    `json:"destination_account"`
+	SourceAsset        string    `json:"source_asset"`
+	DestinationAsset   string    `json:"destination_asset"`
+	Path               []string  `json:"path"`
+	SourceAmount       float64   `json:"source_amount"`
+	DestinationAmount  float64   `json:"destination_amount"`
+}
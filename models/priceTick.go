@@ -3,16 +3,16 @@ package models
 import "time"
 
 type PriceTick struct {
-	ID          uint64    `db:"id"`
-	Timestamp   time.Time `db:"ts"`
-	AssetID     string    `db:"asset_id"`
-	SourceID    string    `db:"source_id"`
-	SourceType  string    `db:"source_type"`
-	PriceUSD    float64   `db:"price_usd"`
-	VolumeUSD   *float64  `db:"volume_usd"`
-	BaseVolume  *float64  `db:"base_volume"`
-	QuoteVolume *float64  `db:"quote_volume"`
-	LedgerSeq   uint32    `db:"ledger_seq"`
-	TxHash      string    `db:"tx_hash"`
-	IngestedAt  time.Time `db:"ingested_at"`
+	ID          uint64    `db:"id" json:"-"`
+	Timestamp   time.Time `db:"ts" json:"ts"`
+	AssetID     string    `db:"asset_id" json:"asset_id"`
+	SourceID    string    `db:"source_id" json:"source_id"`
+	SourceType  string    `db:"source_type" json:"source_type"`
+	PriceUSD    float64   `db:"price_usd" json:"price_usd"`
+	VolumeUSD   *float64  `db:"volume_usd" json:"volume_usd"`
+	BaseVolume  *float64  `db:"base_volume" json:"base_volume"`
+	QuoteVolume *float64  `db:"quote_volume" json:"quote_volume"`
+	LedgerSeq   uint32    `db:"ledger_seq" json:"ledger_seq"`
+	TxHash      string    `db:"tx_hash" json:"tx_hash"`
+	IngestedAt  time.Time `db:"ingested_at" json:"-"`
 }
@@ -0,0 +1,45 @@
+package models
+
+import "fmt"
+
+// Row is a typed record derived from a ledger by a processor.
+type Row interface {
+	TableName() string
+}
+
+func (TransactionModels) TableName() string { return "transaction_models" }
+
+func (PriceTick) TableName() string { return "price_ticks" }
+
+func (Trade) TableName() string { return "trades" }
+
+func (PathPayment) TableName() string { return "path_payments" }
+
+func (LiquidityPoolOperation) TableName() string { return "liquidity_pool_operations" }
+
+func (Offer) TableName() string { return "offers" }
+
+func (Ledger) TableName() string { return "ledgers" }
+
+func (FailedOperation) TableName() string { return "failed_operations" }
+
+func (TokenInfo) TableName() string { return "token_info" }
+
+func (LiquidityPool) TableName() string { return "liquidity_pools" }
+
+// RowScope identifies the stored rows a processor derives: the rows of Table
+// whose Column equals Value, addressed by ledger through LedgerColumn. An
+// empty Column scopes every row of Table.
+type RowScope struct {
+	Table        string
+	LedgerColumn string
+	Column       string
+	Value        string
+}
+
+func (s RowScope) String() string {
+	if s.Column == "" {
+		return s.Table
+	}
+	return fmt.Sprintf("%s (%s=%s)", s.Table, s.Column, s.Value)
+}
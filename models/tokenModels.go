@@ -1,7 +1,5 @@
 package models
 
-import "time"
-
 type TokenInfo struct {
 	ContractAddress string           `json:"contract_address"`
 	Symbol          string           `json:"symbol"`
@@ -24,10 +22,3 @@ type SupplyBreakdown struct {
 	ClaimableBalances float64 `json:"claimable_balances"` // In claimable balances
 	Total             float64 `json:"total"`
 }
-
-// Config for the helper
-type GetTokenConfig struct {
-	RPCUrl     string
-	HorizonUrl string
-	Timeout    time.Duration
-}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Trade is one fill: a single ClaimAtom of an operation that crossed the
+// order book. The pair is in canonical asset order, so every fill of a
+// market lands under the same base and quote whichever side the taker was
+// on. BaseIsSeller is true when the maker sold the base asset. A fill against
+// a liquidity pool has the pool as maker and no offer ID.
+type Trade struct {
+	BlockTime       time.Time `json:"block_time"`
+	LedgerSequence  uint32    `json:"ledger_sequence"`
+	TransactionHash string    `json:"transaction_hash"`
+	OperationIndex  int       `json:"operation_index"`
+	ClaimIndex      int       `json:"claim_index"`
+	OperationType   string    `json:"operation_type"`
+	TakerAccount    string    `json:"taker_account"`
+	MakerAccount    string    `json:"maker_account"`
+	MakerOfferID    uint64    `json:"maker_offer_id"`
+	LiquidityPoolID string    `json:"liquidity_pool_id"`
+	BaseAsset       string    `json:"base_asset"`
+	QuoteAsset      string    `json:"quote_asset"`
+	BaseAmount      float64   `json:"base_amount"`
+	QuoteAmount     float64   `json:"quote_amount"`
+	Price           float64   `json:"price"` // quote per base
+	BaseIsSeller    bool      `json:"base_is_seller"`
+}
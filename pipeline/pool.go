@@ -0,0 +1,138 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/celerfi/stellar-indexer-go/models"
+)
+
+// Task is one unit of handler work. It returns the rows it derived.
+type Task func() (models.LedgerBatch, error)
+
+type job struct {
+	ledger *Ledger
+	slot   int
+	task   Task
+}
+
+// Pool runs handler tasks on a fixed number of workers. Submit blocks while
+// the queue is full, which in turn slows down ledger fetching.
+type Pool struct {
+	jobs     chan job
+	wg       sync.WaitGroup
+	inFlight atomic.Int64
+}
+
+func NewPool(workers, queueSize int) *Pool {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < 0 {
+		queueSize = 0
+	}
+
+	p := &Pool{jobs: make(chan job, queueSize)}
+	for i := 0; i < workers; i++ {
+		p.wg.Add(1)
+		go p.work()
+	}
+	return p
+}
+
+// Submit queues a task for the given ledger. Results are merged back in
+// submission order when the ledger is waited on.
+func (p *Pool) Submit(ctx context.Context, ledger *Ledger, task Task) error {
+	ledger.lock.Lock()
+	slot := len(ledger.results)
+	ledger.results = append(ledger.results, models.LedgerBatch{})
+	ledger.lock.Unlock()
+
+	ledger.wg.Add(1)
+	p.inFlight.Add(1)
+	select {
+	case p.jobs <- job{ledger: ledger, slot: slot, task: task}:
+		return nil
+	case <-ctx.Done():
+		p.inFlight.Add(-1)
+		ledger.wg.Done()
+		return ctx.Err()
+	}
+}
+
+// QueueDepth is the number of tasks waiting for a worker.
+func (p *Pool) QueueDepth() int {
+	return len(p.jobs)
+}
+
+// InFlight is the number of tasks queued or running.
+func (p *Pool) InFlight() int64 {
+	return p.inFlight.Load()
+}
+
+// Close stops accepting work and waits for the workers to exit.
+func (p *Pool) Close() {
+	close(p.jobs)
+	p.wg.Wait()
+}
+
+func (p *Pool) work() {
+	defer p.wg.Done()
+	for j := range p.jobs {
+		rows, err := runTask(j.task)
+		p.inFlight.Add(-1)
+		j.ledger.finish(j.slot, rows, err)
+	}
+}
+
+// runTask shields the pool from handlers that panic on malformed XDR.
+func runTask(task Task) (rows models.LedgerBatch, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panicked: %v", r)
+		}
+	}()
+	return task()
+}
+
+// Ledger is the barrier for every task spawned for one ledger.
+type Ledger struct {
+	sequence uint32
+
+	wg      sync.WaitGroup
+	lock    sync.Mutex
+	results []models.LedgerBatch
+	errs    []error
+}
+
+func NewLedger(sequence uint32) *Ledger {
+	return &Ledger{sequence: sequence}
+}
+
+func (l *Ledger) finish(slot int, rows models.LedgerBatch, err error) {
+	l.lock.Lock()
+	l.results[slot] = rows
+	if err != nil {
+		l.errs = append(l.errs, err)
+	}
+	l.lock.Unlock()
+	l.wg.Done()
+}
+
+// Wait blocks until every task of the ledger has finished or failed and
+// returns the merged rows together with the joined task errors.
+func (l *Ledger) Wait() (models.LedgerBatch, error) {
+	l.wg.Wait()
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	batch := models.LedgerBatch{LedgerSequence: l.sequence}
+	for _, rows := range l.results {
+		batch.Append(rows)
+	}
+	return batch, errors.Join(l.errs...)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/celerfi/stellar-indexer-go/config"
+	"github.com/celerfi/stellar-indexer-go/health"
+	"github.com/celerfi/stellar-indexer-go/logging"
+	"github.com/celerfi/stellar-indexer-go/metrics"
+)
+
+// startHTTPServer serves the operational endpoints in the background.
+func startHTTPServer(cfg config.HTTPConfig, pingSink func(context.Context) error) *http.Server {
+	addr := cfg.Addr
+	if addr == "off" {
+		return nil
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	mux.HandleFunc("/healthz", health.Healthz)
+	mux.Handle("/readyz", health.Readyz(cfg.ReadyMaxLag, pingSink))
+
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("HTTP server stopped", "addr", addr, logging.Err(err))
+		}
+	}()
+	slog.Info("Serving /metrics, /healthz and /readyz", "addr", addr)
+	return server
+}
+
+// trackLatestLedger polls the ledger source for its newest ledger so the
+// lag gauge and /readyz stay current even while ingestion is stuck.
+func trackLatestLedger(ctx context.Context, interval time.Duration, latestLedger func(context.Context) (uint32, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		latest, err := latestLedger(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		health.RecordLatestLedger(latest, err)
+		if err == nil {
+			metrics.SetLatestLedger(latest)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
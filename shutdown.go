@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// drainContext is used for work already started when ctx is cancelled by a
+// shutdown signal: it outlives ctx by timeout so the ledger in flight can
+// finish its handlers and commit, but is cut off if that takes too long.
+func drainContext(ctx context.Context, timeout time.Duration) context.Context {
+	drain, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	context.AfterFunc(ctx, func() {
+		time.AfterFunc(timeout, cancel)
+	})
+	return drain
+}
+
+// shutdownServer stops the HTTP server started by startHTTPServer, if any.
+func shutdownServer(server *http.Server, timeout time.Duration) {
+	if server == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	server.Shutdown(ctx)
+}
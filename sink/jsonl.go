@@ -0,0 +1,188 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/celerfi/stellar-indexer-go/models"
+)
+
+// JSONL appends rows as newline delimited JSON to one file per table and
+// block of rotateLedgers ledgers:
+//
+//	<dir>/<table>/<first ledger>-<last ledger>.jsonl
+//
+// A file is complete once every cursor writing to it has passed its last
+// ledger. Cursors are kept in <dir>/cursors and are advanced after the rows
+// were written, so rows of a ledger interrupted by a crash are written again
+// on restart: delivery is at least once.
+type JSONL struct {
+	lock          sync.Mutex
+	dir           string
+	rotateLedgers uint32
+}
+
+func NewJSONL(dir string, rotateLedgers uint32) (*JSONL, error) {
+	if rotateLedgers == 0 {
+		return nil, errors.New("jsonl sink needs a positive rotation")
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "cursors"), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create jsonl sink directory: %w", err)
+	}
+	return &JSONL{dir: dir, rotateLedgers: rotateLedgers}, nil
+}
+
+func (s *JSONL) WriteLedger(ctx context.Context, batch models.LedgerBatch, cursorName string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	last, err := s.readCursor(cursorName)
+	if err != nil {
+		return err
+	}
+	if last >= batch.LedgerSequence {
+		return fmt.Errorf("%w: cursor %s is at or past ledger %d", ErrLedgerAlreadyCommitted, cursorName, batch.LedgerSequence)
+	}
+	if err := s.append(batch.LedgerSequence, batch.Rows()...); err != nil {
+		return err
+	}
+	return s.writeCursor(cursorName, batch.LedgerSequence)
+}
+
+func (s *JSONL) WriteDeadLetter(ctx context.Context, seq uint32, rawMeta []byte, cursorName string, cause error) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := s.append(seq, newDeadLetter(seq, rawMeta, cursorName, cause)); err != nil {
+		return err
+	}
+	if cursorName == "" {
+		return nil
+	}
+	return s.writeCursor(cursorName, seq)
+}
+
+func (s *JSONL) Cursor(ctx context.Context, cursorName string) (uint32, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.readCursor(cursorName)
+}
+
+// Cursors matches prefix against the cursor file names, which replace the
+// separators of a cursor name, so the rest of a name is returned as stored.
+func (s *JSONL) Cursors(ctx context.Context, prefix string) (map[string]uint32, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	entries, err := os.ReadDir(filepath.Join(s.dir, "cursors"))
+	if err != nil {
+		return nil, fmt.Errorf("error reading cursors %s*: %w", prefix, err)
+	}
+	filePrefix := filepath.Base(s.cursorPath(prefix))
+	cursors := map[string]uint32{}
+	for _, entry := range entries {
+		rest, ok := strings.CutPrefix(entry.Name(), filePrefix)
+		if !ok || strings.HasSuffix(rest, ".tmp") {
+			continue
+		}
+		seq, err := s.readCursor(prefix + rest)
+		if err != nil {
+			return nil, err
+		}
+		cursors[rest] = seq
+	}
+	return cursors, nil
+}
+
+// Tokens returns none: the token files are only appended to, so tokens are
+// looked up again after a restart.
+func (s *JSONL) Tokens(ctx context.Context) ([]string, error) { return nil, nil }
+
+func (s *JSONL) Ping(ctx context.Context) error {
+	_, err := os.Stat(filepath.Join(s.dir, "cursors"))
+	return err
+}
+
+func (s *JSONL) Close() error { return nil }
+
+// append writes rows of ledger seq to the files of their tables, one write
+// per file.
+func (s *JSONL) append(seq uint32, rows ...models.Row) error {
+	lines := map[string]*bytes.Buffer{}
+	var tables []string
+	for _, row := range rows {
+		buf, ok := lines[row.TableName()]
+		if !ok {
+			buf = &bytes.Buffer{}
+			lines[row.TableName()] = buf
+			tables = append(tables, row.TableName())
+		}
+		if err := json.NewEncoder(buf).Encode(row); err != nil {
+			return fmt.Errorf("failed to encode %s row of ledger %d: %w", row.TableName(), seq, err)
+		}
+	}
+
+	first := seq - seq%s.rotateLedgers
+	name := fmt.Sprintf("%010d-%010d.jsonl", first, first+s.rotateLedgers-1)
+	for _, table := range tables {
+		if err := appendFile(filepath.Join(s.dir, table), name, lines[table].Bytes()); err != nil {
+			return fmt.Errorf("failed to write %s rows of ledger %d: %w", table, seq, err)
+		}
+	}
+	return nil
+}
+
+func appendFile(dir, name string, data []byte) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, name), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (s *JSONL) cursorPath(cursorName string) string {
+	return filepath.Join(s.dir, "cursors", strings.NewReplacer("/", "_", ":", "_").Replace(cursorName))
+}
+
+func (s *JSONL) readCursor(cursorName string) (uint32, error) {
+	data, err := os.ReadFile(s.cursorPath(cursorName))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error reading cursor %s: %w", cursorName, err)
+	}
+	seq, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("corrupt cursor %s: %w", cursorName, err)
+	}
+	return uint32(seq), nil
+}
+
+// writeCursor replaces the cursor file atomically.
+func (s *JSONL) writeCursor(cursorName string, seq uint32) error {
+	path := s.cursorPath(cursorName)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.FormatUint(uint64(seq), 10)+"\n"), 0o644); err != nil {
+		return fmt.Errorf("error advancing cursor %s: %w", cursorName, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("error advancing cursor %s: %w", cursorName, err)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package sink
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/celerfi/stellar-indexer-go/models"
+)
+
+// Memory keeps every batch written to it in memory, for tests and for
+// inspecting handler output without any storage.
+type Memory struct {
+	lock        sync.Mutex
+	batches     []models.LedgerBatch
+	deadLetters []uint32
+	cursors     map[string]uint32
+}
+
+func NewMemory() *Memory {
+	return &Memory{cursors: map[string]uint32{}}
+}
+
+func (m *Memory) WriteLedger(ctx context.Context, batch models.LedgerBatch, cursorName string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.cursors[cursorName] >= batch.LedgerSequence {
+		return fmt.Errorf("%w: cursor %s is at or past ledger %d", ErrLedgerAlreadyCommitted, cursorName, batch.LedgerSequence)
+	}
+	m.batches = append(m.batches, batch)
+	m.cursors[cursorName] = batch.LedgerSequence
+	return nil
+}
+
+func (m *Memory) WriteDeadLetter(ctx context.Context, seq uint32, rawMeta []byte, cursorName string, cause error) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.deadLetters = append(m.deadLetters, seq)
+	if cursorName != "" {
+		m.cursors[cursorName] = seq
+	}
+	return nil
+}
+
+func (m *Memory) Cursor(ctx context.Context, cursorName string) (uint32, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.cursors[cursorName], nil
+}
+
+func (m *Memory) Cursors(ctx context.Context, prefix string) (map[string]uint32, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return cursorsWithPrefix(m.cursors, prefix), nil
+}
+
+func (m *Memory) Tokens(ctx context.Context) ([]string, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	var tokens []string
+	for _, batch := range m.batches {
+		for _, token := range batch.Tokens {
+			tokens = append(tokens, token.ContractAddress)
+		}
+	}
+	return tokens, nil
+}
+
+func (m *Memory) Ping(ctx context.Context) error { return nil }
+
+func (m *Memory) Close() error { return nil }
+
+// Batches returns the batches written so far in write order.
+func (m *Memory) Batches() []models.LedgerBatch {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return append([]models.LedgerBatch(nil), m.batches...)
+}
+
+// DeadLetters returns the sequences of the dead lettered ledgers.
+func (m *Memory) DeadLetters() []uint32 {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return append([]uint32(nil), m.deadLetters...)
+}
@@ -0,0 +1,56 @@
+package sink
+
+import (
+	"context"
+
+	"github.com/celerfi/stellar-indexer-go/config"
+	"github.com/celerfi/stellar-indexer-go/migrations"
+	"github.com/celerfi/stellar-indexer-go/models"
+	"github.com/celerfi/stellar-indexer-go/utils"
+)
+
+// Postgres commits each ledger's rows and its cursor in one database
+// transaction through the pool opened by utils.OpenDb.
+type Postgres struct{}
+
+// NewPostgres connects to the database and refuses to continue unless its
+// schema is at the version this binary was built for.
+func NewPostgres(ctx context.Context, cfg config.DBConfig) (*Postgres, error) {
+	if err := utils.OpenDb(ctx, cfg); err != nil {
+		return nil, err
+	}
+	if err := migrations.Check(ctx, utils.DbPool()); err != nil {
+		utils.CloseDb()
+		return nil, err
+	}
+	return &Postgres{}, nil
+}
+
+func (*Postgres) WriteLedger(ctx context.Context, batch models.LedgerBatch, cursorName string) error {
+	return utils.CommitLedger(ctx, batch, cursorName)
+}
+
+func (*Postgres) WriteDeadLetter(ctx context.Context, seq uint32, rawMeta []byte, cursorName string, cause error) error {
+	return utils.RecordDeadLetter(ctx, seq, rawMeta, cursorName, cause)
+}
+
+func (*Postgres) Cursor(ctx context.Context, cursorName string) (uint32, error) {
+	return utils.GetCursor(ctx, cursorName)
+}
+
+func (*Postgres) Cursors(ctx context.Context, prefix string) (map[string]uint32, error) {
+	return utils.GetCursors(ctx, prefix)
+}
+
+func (*Postgres) Tokens(ctx context.Context) ([]string, error) {
+	return utils.GetTokenAddresses(ctx)
+}
+
+func (*Postgres) Ping(ctx context.Context) error {
+	return utils.PingDb(ctx)
+}
+
+func (*Postgres) Close() error {
+	utils.CloseDb()
+	return nil
+}
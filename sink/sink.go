@@ -0,0 +1,89 @@
+// Package sink writes the rows derived from each ledger to where they are
+// kept: Postgres, rotated newline delimited JSON files, or stdout.
+package sink
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/celerfi/stellar-indexer-go/config"
+	"github.com/celerfi/stellar-indexer-go/models"
+	"github.com/celerfi/stellar-indexer-go/utils"
+)
+
+// Sink receives the typed rows of every ledger together with the ingestion
+// cursor they belong to. Implementations must be safe for concurrent use,
+// backfill writes several cursors at once.
+type Sink interface {
+	// WriteLedger writes every row of batch and advances cursorName to its
+	// ledger. It returns ErrLedgerAlreadyCommitted, writing nothing, when the
+	// cursor is already at or past the ledger.
+	WriteLedger(ctx context.Context, batch models.LedgerBatch, cursorName string) error
+	// WriteDeadLetter records ledger seq, which could not be decoded or
+	// indexed, with its raw meta as far as it is known. When cursorName is
+	// set the cursor is advanced past the ledger.
+	WriteDeadLetter(ctx context.Context, seq uint32, rawMeta []byte, cursorName string, cause error) error
+	// Cursor returns the last ledger written under cursorName, 0 if none.
+	Cursor(ctx context.Context, cursorName string) (uint32, error)
+	// Cursors returns every cursor whose name starts with prefix, keyed by
+	// the rest of the name.
+	Cursors(ctx context.Context, prefix string) (map[string]uint32, error)
+	// Tokens returns the addresses of the tokens whose details the sink
+	// already holds, so a restart does not look them up again. Sinks that
+	// cannot tell return none.
+	Tokens(ctx context.Context) ([]string, error)
+	// Ping checks that the sink can be written to.
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+// ErrLedgerAlreadyCommitted is returned by WriteLedger for a ledger the
+// cursor has already passed.
+var ErrLedgerAlreadyCommitted = utils.ErrLedgerAlreadyCommitted
+
+// New opens the sink selected by the configuration.
+func New(ctx context.Context, cfg config.Config) (Sink, error) {
+	switch cfg.Sink.Type {
+	case "postgres":
+		return NewPostgres(ctx, cfg.DB)
+	case "jsonl":
+		return NewJSONL(cfg.Sink.Dir, cfg.Sink.RotateLedgers)
+	case "stdout":
+		return NewStdout(os.Stdout), nil
+	default:
+		return nil, fmt.Errorf("unknown sink %q: options (postgres, jsonl, stdout)", cfg.Sink.Type)
+	}
+}
+
+// cursorsWithPrefix picks the cursors of the in-memory sinks starting with
+// prefix, keyed by the rest of the name.
+func cursorsWithPrefix(cursors map[string]uint32, prefix string) map[string]uint32 {
+	matched := map[string]uint32{}
+	for name, seq := range cursors {
+		if rest, ok := strings.CutPrefix(name, prefix); ok {
+			matched[rest] = seq
+		}
+	}
+	return matched
+}
+
+// deadLetter is how the file sinks record a ledger that could not be indexed.
+type deadLetter struct {
+	LedgerSequence uint32 `json:"ledger_sequence"`
+	CursorName     string `json:"cursor_name,omitempty"`
+	Error          string `json:"error"`
+	RawMeta        []byte `json:"raw_meta,omitempty"`
+}
+
+func (deadLetter) TableName() string { return "dead_letter_ledgers" }
+
+func newDeadLetter(seq uint32, rawMeta []byte, cursorName string, cause error) deadLetter {
+	return deadLetter{
+		LedgerSequence: seq,
+		CursorName:     cursorName,
+		Error:          cause.Error(),
+		RawMeta:        rawMeta,
+	}
+}
@@ -0,0 +1,83 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/celerfi/stellar-indexer-go/models"
+)
+
+// Stdout is a dry run: every row is printed as a JSON line naming its table
+// and nothing is stored. Cursors live in memory only, so a restart begins
+// from scratch.
+type Stdout struct {
+	lock    sync.Mutex
+	w       io.Writer
+	cursors map[string]uint32
+}
+
+type stdoutLine struct {
+	Table string     `json:"table"`
+	Row   models.Row `json:"row"`
+}
+
+func NewStdout(w io.Writer) *Stdout {
+	return &Stdout{w: w, cursors: map[string]uint32{}}
+}
+
+func (s *Stdout) WriteLedger(ctx context.Context, batch models.LedgerBatch, cursorName string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.cursors[cursorName] >= batch.LedgerSequence {
+		return fmt.Errorf("%w: cursor %s is at or past ledger %d", ErrLedgerAlreadyCommitted, cursorName, batch.LedgerSequence)
+	}
+	if err := s.write(batch.Rows()...); err != nil {
+		return err
+	}
+	s.cursors[cursorName] = batch.LedgerSequence
+	return nil
+}
+
+func (s *Stdout) WriteDeadLetter(ctx context.Context, seq uint32, rawMeta []byte, cursorName string, cause error) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	letter := newDeadLetter(seq, nil, cursorName, cause)
+	if err := s.write(letter); err != nil {
+		return err
+	}
+	if cursorName != "" {
+		s.cursors[cursorName] = seq
+	}
+	return nil
+}
+
+func (s *Stdout) Cursor(ctx context.Context, cursorName string) (uint32, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.cursors[cursorName], nil
+}
+
+func (s *Stdout) Cursors(ctx context.Context, prefix string) (map[string]uint32, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return cursorsWithPrefix(s.cursors, prefix), nil
+}
+
+func (s *Stdout) Tokens(ctx context.Context) ([]string, error) { return nil, nil }
+
+func (s *Stdout) Ping(ctx context.Context) error { return nil }
+
+func (s *Stdout) Close() error { return nil }
+
+func (s *Stdout) write(rows ...models.Row) error {
+	enc := json.NewEncoder(s.w)
+	for _, row := range rows {
+		if err := enc.Encode(stdoutLine{Table: row.TableName(), Row: row}); err != nil {
+			return fmt.Errorf("failed to write %s row: %w", row.TableName(), err)
+		}
+	}
+	return nil
+}
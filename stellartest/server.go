@@ -0,0 +1,419 @@
+// Package stellartest serves a scripted Soroban RPC node and Horizon from an
+// in-process httptest server, so the RPC and Horizon helpers can be tested
+// without a network.
+package stellartest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+
+	hProtocol "github.com/stellar/go/protocols/horizon"
+	protocol "github.com/stellar/go/protocols/rpc"
+	"github.com/stellar/go/xdr"
+)
+
+// Server fakes the RPC methods and Horizon routes the indexer uses:
+// simulateTransaction, getHealth, getLedgers and getLedgerEntries on RPCURL,
+// and /assets and /accounts/{id} on HorizonURL. Everything it answers with is
+// scripted through its setters; anything not scripted is an error or a 404.
+type Server struct {
+	server *httptest.Server
+
+	mu           sync.Mutex
+	results      map[string]xdr.ScVal
+	simErrors    map[string]string
+	ledgers      map[uint32]xdr.LedgerCloseMeta
+	latestLedger uint32
+	entries      map[string]ledgerEntry
+	assets       []hProtocol.AssetStat
+	accounts     map[string]hProtocol.Account
+	calls        map[string]int
+}
+
+type ledgerEntry struct {
+	key                string
+	entry              xdr.LedgerEntry
+	lastModifiedLedger uint32
+}
+
+// NewServer starts a fake with nothing scripted. Close it when done.
+func NewServer() *Server {
+	s := &Server{
+		results:   map[string]xdr.ScVal{},
+		simErrors: map[string]string{},
+		ledgers:   map[uint32]xdr.LedgerCloseMeta{},
+		entries:   map[string]ledgerEntry{},
+		accounts:  map[string]hProtocol.Account{},
+		calls:     map[string]int{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /rpc", s.serveRPC)
+	mux.HandleFunc("GET /assets", s.serveAssets)
+	mux.HandleFunc("GET /accounts/{id}", s.serveAccount)
+	s.server = httptest.NewServer(mux)
+	return s
+}
+
+// RPCURL is the JSON-RPC endpoint of the fake RPC node.
+func (s *Server) RPCURL() string { return s.server.URL + "/rpc" }
+
+// HorizonURL is the root of the fake Horizon.
+func (s *Server) HorizonURL() string { return s.server.URL }
+
+func (s *Server) Close() { s.server.Close() }
+
+// SetContractResult makes simulating function on contract return val.
+func (s *Server) SetContractResult(contract, function string, val xdr.ScVal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.results[contract+"/"+function] = val
+	delete(s.simErrors, contract+"/"+function)
+}
+
+// SetContractError makes simulating function on contract fail with msg, the
+// way a contract panic or a missing function is reported by a real node.
+func (s *Server) SetContractError(contract, function, msg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.simErrors[contract+"/"+function] = msg
+	delete(s.results, contract+"/"+function)
+}
+
+// AddLedger makes ledger available through getLedgers. The latest ledger
+// reported by getHealth follows the highest ledger added unless
+// SetLatestLedger was called.
+func (s *Server) AddLedger(ledger xdr.LedgerCloseMeta) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ledgers[ledger.LedgerSequence()] = ledger
+	s.latestLedger = max(s.latestLedger, ledger.LedgerSequence())
+}
+
+// SetLatestLedger sets the latest ledger getHealth and getLedgers report.
+func (s *Server) SetLatestLedger(seq uint32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latestLedger = seq
+}
+
+// SetLedgerEntry makes entry available through getLedgerEntries under its
+// ledger key.
+func (s *Server) SetLedgerEntry(entry xdr.LedgerEntry) error {
+	key, err := entry.LedgerKey()
+	if err != nil {
+		return err
+	}
+	keyXDR, err := xdr.MarshalBase64(key)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[keyXDR] = ledgerEntry{key: keyXDR, entry: entry, lastModifiedLedger: uint32(entry.LastModifiedLedgerSeq)}
+	return nil
+}
+
+// AddAsset lists asset on /assets.
+func (s *Server) AddAsset(asset hProtocol.AssetStat) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.assets = append(s.assets, asset)
+}
+
+// AddAccount serves account on /accounts/{account.AccountID}.
+func (s *Server) AddAccount(account hProtocol.Account) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accounts[account.AccountID] = account
+}
+
+// Calls is how many times method was called: an RPC method name, or
+// "simulateTransaction:<function>" per simulated contract function, or a
+// Horizon route ("/assets", "/accounts").
+func (s *Server) Calls(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[method]
+}
+
+type rpcRequest struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// JSON-RPC error codes the fake answers with.
+const (
+	codeInvalidParams  = -32602
+	codeMethodNotFound = -32601
+)
+
+func (s *Server) serveRPC(w http.ResponseWriter, r *http.Request) {
+	var body json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Clients may send a single call or a batch.
+	batch := bytes.HasPrefix(bytes.TrimSpace(body), []byte("["))
+	var requests []rpcRequest
+	if batch {
+		if err := json.Unmarshal(body, &requests); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else {
+		var request rpcRequest
+		if err := json.Unmarshal(body, &request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		requests = []rpcRequest{request}
+	}
+
+	responses := make([]rpcResponse, 0, len(requests))
+	for _, request := range requests {
+		response := rpcResponse{JSONRPC: "2.0", ID: request.ID}
+		result, err := s.call(request.Method, request.Params)
+		if err != nil {
+			response.Error = err
+		} else {
+			response.Result = result
+		}
+		responses = append(responses, response)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if batch {
+		json.NewEncoder(w).Encode(responses)
+		return
+	}
+	json.NewEncoder(w).Encode(responses[0])
+}
+
+func (s *Server) call(method string, params json.RawMessage) (any, *rpcError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls[method]++
+
+	switch method {
+	case protocol.GetHealthMethodName:
+		return s.health(), nil
+	case protocol.SimulateTransactionMethodName:
+		var request protocol.SimulateTransactionRequest
+		if err := json.Unmarshal(params, &request); err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+		}
+		return s.simulate(request)
+	case protocol.GetLedgersMethodName:
+		var request protocol.GetLedgersRequest
+		if err := json.Unmarshal(params, &request); err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+		}
+		return s.getLedgers(request)
+	case protocol.GetLedgerEntriesMethodName:
+		var request protocol.GetLedgerEntriesRequest
+		if err := json.Unmarshal(params, &request); err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+		}
+		return s.getLedgerEntries(request), nil
+	default:
+		return nil, &rpcError{Code: codeMethodNotFound, Message: fmt.Sprintf("method %q not found", method)}
+	}
+}
+
+func (s *Server) oldestLedger() uint32 {
+	oldest := s.latestLedger
+	for seq := range s.ledgers {
+		oldest = min(oldest, seq)
+	}
+	return oldest
+}
+
+func (s *Server) health() protocol.GetHealthResponse {
+	oldest := s.oldestLedger()
+	return protocol.GetHealthResponse{
+		Status:                "healthy",
+		LatestLedger:          s.latestLedger,
+		OldestLedger:          oldest,
+		LedgerRetentionWindow: s.latestLedger - oldest + 1,
+	}
+}
+
+func (s *Server) simulate(request protocol.SimulateTransactionRequest) (any, *rpcError) {
+	var envelope xdr.TransactionEnvelope
+	if err := xdr.SafeUnmarshalBase64(request.Transaction, &envelope); err != nil {
+		return nil, &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("could not unmarshal transaction: %v", err)}
+	}
+	ops := envelope.Operations()
+	if len(ops) != 1 || ops[0].Body.Type != xdr.OperationTypeInvokeHostFunction {
+		return nil, &rpcError{Code: codeInvalidParams, Message: "transaction must contain exactly one InvokeHostFunction operation"}
+	}
+	invoke, ok := ops[0].Body.MustInvokeHostFunctionOp().HostFunction.GetInvokeContract()
+	if !ok {
+		return protocol.SimulateTransactionResponse{Error: "only contract invocations are supported", LatestLedger: s.latestLedger}, nil
+	}
+	contract, err := invoke.ContractAddress.String()
+	if err != nil {
+		return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	function := string(invoke.FunctionName)
+	s.calls[protocol.SimulateTransactionMethodName+":"+function]++
+
+	key := contract + "/" + function
+	if msg, ok := s.simErrors[key]; ok {
+		return protocol.SimulateTransactionResponse{Error: msg, LatestLedger: s.latestLedger}, nil
+	}
+	val, ok := s.results[key]
+	if !ok {
+		return protocol.SimulateTransactionResponse{
+			Error:        fmt.Sprintf("HostError: Error(Context, MissingValue): %s has no function %s", contract, function),
+			LatestLedger: s.latestLedger,
+		}, nil
+	}
+	valXDR, err := xdr.MarshalBase64(val)
+	if err != nil {
+		return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return protocol.SimulateTransactionResponse{
+		Results:      []protocol.SimulateHostFunctionResult{{ReturnValueXDR: &valXDR}},
+		LatestLedger: s.latestLedger,
+	}, nil
+}
+
+// defaultLedgersLimit matches the page size of a real node.
+const defaultLedgersLimit = 5
+
+func (s *Server) getLedgers(request protocol.GetLedgersRequest) (any, *rpcError) {
+	oldest := s.oldestLedger()
+	if request.StartLedger < oldest || request.StartLedger > s.latestLedger {
+		return nil, &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf(
+			"start ledger (%d) must be between the oldest ledger: %d and the latest ledger: %d for this rpc instance",
+			request.StartLedger, oldest, s.latestLedger)}
+	}
+	limit := uint(defaultLedgersLimit)
+	if request.Pagination != nil && request.Pagination.Limit > 0 {
+		limit = request.Pagination.Limit
+	}
+
+	response := protocol.GetLedgersResponse{
+		Ledgers:      []protocol.LedgerInfo{},
+		LatestLedger: s.latestLedger,
+		OldestLedger: oldest,
+	}
+	for seq := request.StartLedger; seq <= s.latestLedger && uint(len(response.Ledgers)) < limit; seq++ {
+		ledger, ok := s.ledgers[seq]
+		if !ok {
+			break
+		}
+		metaXDR, err := xdr.MarshalBase64(ledger)
+		if err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+		}
+		headerXDR, err := xdr.MarshalBase64(ledger.LedgerHeaderHistoryEntry())
+		if err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+		}
+		response.Ledgers = append(response.Ledgers, protocol.LedgerInfo{
+			Hash:            ledger.LedgerHash().HexString(),
+			Sequence:        seq,
+			LedgerCloseTime: ledger.ClosedAt().Unix(),
+			LedgerHeader:    headerXDR,
+			LedgerMetadata:  metaXDR,
+		})
+		response.Cursor = fmt.Sprint(seq)
+	}
+	return response, nil
+}
+
+func (s *Server) getLedgerEntries(request protocol.GetLedgerEntriesRequest) protocol.GetLedgerEntriesResponse {
+	response := protocol.GetLedgerEntriesResponse{
+		Entries:      []protocol.LedgerEntryResult{},
+		LatestLedger: s.latestLedger,
+	}
+	for _, key := range request.Keys {
+		found, ok := s.entries[key]
+		if !ok {
+			continue
+		}
+		dataXDR, err := xdr.MarshalBase64(found.entry.Data)
+		if err != nil {
+			continue
+		}
+		response.Entries = append(response.Entries, protocol.LedgerEntryResult{
+			KeyXDR:             found.key,
+			DataXDR:            dataXDR,
+			LastModifiedLedger: found.lastModifiedLedger,
+		})
+	}
+	return response
+}
+
+func (s *Server) serveAssets(w http.ResponseWriter, r *http.Request) {
+	code := r.URL.Query().Get("asset_code")
+	issuer := r.URL.Query().Get("asset_issuer")
+
+	s.mu.Lock()
+	s.calls["/assets"]++
+	var page hProtocol.AssetsPage
+	for _, asset := range s.assets {
+		if (code == "" || asset.Code == code) && (issuer == "" || asset.Issuer == issuer) {
+			page.Embedded.Records = append(page.Embedded.Records, asset)
+		}
+	}
+	s.mu.Unlock()
+
+	sort.SliceStable(page.Embedded.Records, func(i, j int) bool {
+		return page.Embedded.Records[i].Code < page.Embedded.Records[j].Code
+	})
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (s *Server) serveAccount(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSpace(r.PathValue("id"))
+
+	s.mu.Lock()
+	s.calls["/accounts"]++
+	account, ok := s.accounts[id]
+	s.mu.Unlock()
+
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]any{
+			"type":   "https://stellar.org/horizon-errors/not_found",
+			"title":  "Resource Missing",
+			"status": http.StatusNotFound,
+			"detail": "The resource at the url requested was not found.",
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, account)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	if status == http.StatusOK {
+		w.Header().Set("Content-Type", "application/hal+json; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", "application/problem+json; charset=utf-8")
+	}
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
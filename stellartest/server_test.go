@@ -0,0 +1,77 @@
+package stellartest
+
+import (
+	"context"
+	"crypto/sha256"
+	"testing"
+
+	rpcclient "github.com/stellar/go/clients/rpcclient"
+	protocol "github.com/stellar/go/protocols/rpc"
+	"github.com/stellar/go/xdr"
+)
+
+func TestGetLedgerEntries(t *testing.T) {
+	fake := NewServer()
+	defer fake.Close()
+	fake.SetLatestLedger(500)
+
+	var accountKey xdr.Uint256 = sha256.Sum256([]byte("account"))
+	entry := xdr.LedgerEntry{
+		LastModifiedLedgerSeq: 420,
+		Data: xdr.LedgerEntryData{
+			Type: xdr.LedgerEntryTypeAccount,
+			Account: &xdr.AccountEntry{
+				AccountId: xdr.AccountId{Type: xdr.PublicKeyTypePublicKeyTypeEd25519, Ed25519: &accountKey},
+				Balance:   1_000_0000000,
+			},
+		},
+	}
+	if err := fake.SetLedgerEntry(entry); err != nil {
+		t.Fatal(err)
+	}
+	key, err := entry.LedgerKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyXDR, err := xdr.MarshalBase64(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var missing xdr.Uint256 = sha256.Sum256([]byte("missing"))
+	missingXDR, err := xdr.MarshalBase64(xdr.LedgerKey{
+		Type:    xdr.LedgerEntryTypeAccount,
+		Account: &xdr.LedgerKeyAccount{AccountId: xdr.AccountId{Type: xdr.PublicKeyTypePublicKeyTypeEd25519, Ed25519: &missing}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := rpcclient.NewClient(fake.RPCURL(), nil)
+	defer client.Close()
+	response, err := client.GetLedgerEntries(context.Background(), protocol.GetLedgerEntriesRequest{Keys: []string{keyXDR, missingXDR}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.LatestLedger != 500 || len(response.Entries) != 1 {
+		t.Fatalf("got latest %d with %d entries, want 500 with 1", response.LatestLedger, len(response.Entries))
+	}
+	got := response.Entries[0]
+	var data xdr.LedgerEntryData
+	if err := xdr.SafeUnmarshalBase64(got.DataXDR, &data); err != nil {
+		t.Fatal(err)
+	}
+	if got.KeyXDR != keyXDR || got.LastModifiedLedger != 420 || data.MustAccount().Balance != entry.Data.MustAccount().Balance {
+		t.Errorf("got entry %+v, want the scripted account", got)
+	}
+}
+
+func TestUnknownMethod(t *testing.T) {
+	fake := NewServer()
+	defer fake.Close()
+
+	client := rpcclient.NewClient(fake.RPCURL(), nil)
+	defer client.Close()
+	if _, err := client.GetNetwork(context.Background()); err == nil {
+		t.Error("want an error for a method the fake does not implement")
+	}
+}
@@ -3,228 +3,806 @@ package utils
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/celerfi/stellar-indexer-go/config"
+	"github.com/celerfi/stellar-indexer-go/metrics"
 	"github.com/celerfi/stellar-indexer-go/models"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-var db = connectToDb()
+var db *pgxpool.Pool
 
-func connectToDb() *pgxpool.Pool {
-	databaseUrl := fmt.Sprintf("postgres://%s:%s@%s:5432/%s", config.DB_USER, config.DB_PASSWORD, config.DB_HOST, config.DB_NAME)
-	ctx := context.Background()
-
-	poolConfig, err := pgxpool.ParseConfig(databaseUrl)
+// OpenDb connects the package's pool to the configured database and checks
+// that it is reachable. Call it once at startup.
+func OpenDb(ctx context.Context, cfg config.DBConfig) error {
+	poolConfig, err := pgxpool.ParseConfig(cfg.DSN())
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Unable to parse database config: %v\n", err)
-		os.Exit(1)
+		return fmt.Errorf("unable to parse database config: %w", err)
 	}
+	poolConfig.MaxConns = int32(cfg.MaxConns)
+	poolConfig.MinConns = int32(cfg.MinConns)
 
-	poolConfig.MaxConns = 5
 	dbPool, err := pgxpool.NewWithConfig(ctx, poolConfig)
-
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Unable to create connection pool: %v\n", err)
-		os.Exit(1)
+		return fmt.Errorf("unable to create connection pool: %w", err)
+	}
+	if err := dbPool.Ping(ctx); err != nil {
+		dbPool.Close()
+		return fmt.Errorf("unable to reach database %s on %s: %w", cfg.Name, cfg.Host, err)
 	}
 
-	fmt.Println("Successfully connected to database!")
-	return dbPool
+	db = dbPool
+	slog.Info("Connected to database", "host", cfg.Host, "database", cfg.Name)
+	return nil
 }
 
-func InsertTransactionsToDb(transactions []models.TransactionModels) {
-	if len(transactions) == 0 {
-		return
-	}
+// DbPool returns the pool opened by OpenDb.
+func DbPool() *pgxpool.Pool {
+	return db
+}
 
-	tx, err := db.Begin(context.Background())
-	if err != nil {
-		fmt.Printf("Error starting transaction: %v\n", err)
-		return
+// CloseDb closes the pool opened by OpenDb.
+func CloseDb() {
+	if db != nil {
+		db.Close()
 	}
+}
+
+// PingDb checks that the pool can reach the database.
+func PingDb(ctx context.Context) error {
+	return db.Ping(ctx)
+}
+
+// ErrLedgerAlreadyCommitted is returned by CommitLedger when the cursor is
+// already at or past the ledger, so its rows are not written a second time.
+var ErrLedgerAlreadyCommitted = errors.New("ledger already committed")
+
+// CommitLedger writes every row derived from a ledger and advances the named
+// ingestion cursor in a single database transaction. Either all of it is
+// visible afterwards or none of it is.
+func CommitLedger(ctx context.Context, batch models.LedgerBatch, cursorName string) (err error) {
 	defer func() {
-		if r := recover(); r != nil {
-			fmt.Printf("Recovered from panic in InsertTransactionsToDb, rolling back: %v\n", r)
-			tx.Rollback(context.Background())
+		if err != nil && !errors.Is(err, ErrLedgerAlreadyCommitted) {
+			metrics.DBErrors.WithLabelValues("commit_ledger").Inc()
 		}
 	}()
 
-	err = func() error {
-		_, err = tx.CopyFrom(
-			context.Background(),
-			pgx.Identifier{"transaction_models"},
-			[]string{
-				"block_time", "ledger_sequence", "transaction_hash", "operation_index",
-				"dex_name", "source_account", "token_in", "token_out", "offer_id",
-				"dex_type", "pool_address", "matched_offer_id", "buyer_account",
-				"seller_account", "offer_buy_amount", "offer_sell_amount", "amount_bought",
-				"amount_sold", "offer_price", "dex_fee", "status", "order_matches",
-			},
-			pgx.CopyFromSlice(len(transactions), func(i int) ([]interface{}, error) {
-				transaction := transactions[i]
-				orderMatchesJSON, err := json.Marshal(transaction.OrderMatches)
-				if err != nil {
-					return nil, fmt.Errorf("failed to marshal order matches to JSON: %w", err)
-				}
-
-				return []interface{}{
-					transaction.BlockTime, transaction.LedgerSequence, transaction.TransactionHash, transaction.OperationIndex,
-					transaction.DexName, transaction.SourceAccount, transaction.TokenIn, transaction.TokenOut, transaction.OfferID,
-					transaction.Dex_type, transaction.PoolAddress, transaction.MatchedOfferID, transaction.BuyerAccount,
-					transaction.SellerAccount, transaction.OfferBuyAmount, transaction.OfferSellAmount, transaction.AmountBought,
-					transaction.AmountSold, transaction.OfferPrice, transaction.DexFee, transaction.Status, orderMatchesJSON,
-				}, nil
-			}),
-		)
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := advanceCursor(ctx, tx, cursorName, batch.LedgerSequence); err != nil {
+		return err
+	}
+	written, err := insertBatch(ctx, tx, batch)
+	if err != nil {
 		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error committing ledger %d: %w", batch.LedgerSequence, err)
+	}
+	for table, count := range written {
+		metrics.RowsWritten.WithLabelValues(table).Add(float64(count))
+	}
+	return nil
+}
+
+// RecordDeadLetter stores a ledger that could not be decoded together with
+// its raw meta and the cause. When cursorName is set the cursor is advanced
+// past the ledger in the same transaction, so ingestion moves on without
+// losing track of it.
+func RecordDeadLetter(ctx context.Context, seq uint32, rawMeta []byte, cursorName string, cause error) (err error) {
+	defer func() {
+		if err != nil {
+			metrics.DBErrors.WithLabelValues("dead_letter").Inc()
+		}
 	}()
 
+	tx, err := db.Begin(ctx)
 	if err != nil {
-		fmt.Printf("Error inserting transactions, rolling back: %v\n", err)
-		tx.Rollback(context.Background())
-		return
+		return fmt.Errorf("error starting transaction: %w", err)
 	}
+	defer tx.Rollback(ctx)
 
-	err = tx.Commit(context.Background())
+	if cursorName != "" {
+		if err := advanceCursor(ctx, tx, cursorName, seq); err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec(
+		ctx,
+		`INSERT INTO dead_letter_ledgers (ledger_sequence, cursor_name, error, raw_meta)
+		VALUES ($1, NULLIF($2, ''), $3, $4)`,
+		seq, cursorName, cause.Error(), rawMeta,
+	)
 	if err != nil {
-		fmt.Printf("Error committing transaction: %v\n", err)
+		return fmt.Errorf("error recording dead letter for ledger %d: %w", seq, err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error committing dead letter for ledger %d: %w", seq, err)
 	}
+	metrics.DeadLetterLedgers.Inc()
+	return nil
 }
 
-func TokenExistsInDb(tokenHash string) bool {
-	var exists bool
-	err := db.QueryRow(context.Background(), "SELECT EXISTS(SELECT 1 FROM token_info WHERE contract_address = $1)", tokenHash).Scan(&exists)
+// insertBatch writes every row of the batch inside tx and returns how many
+// new rows landed in each table.
+func insertBatch(ctx context.Context, tx pgx.Tx, batch models.LedgerBatch) (map[string]int64, error) {
+	written := map[string]int64{}
+
+	if batch.Ledger != nil {
+		start := time.Now()
+		if err := upsertLedger(ctx, tx, *batch.Ledger); err != nil {
+			return nil, fmt.Errorf("error inserting ledger %d: %w", batch.LedgerSequence, err)
+		}
+		metrics.DBInsertDuration.WithLabelValues("ledgers").Observe(time.Since(start).Seconds())
+		written["ledgers"] = 1
+	}
+
+	start := time.Now()
+	count, err := insertTransactions(ctx, tx, batch.Transactions)
+	if err != nil {
+		return nil, fmt.Errorf("error inserting transactions for ledger %d: %w", batch.LedgerSequence, err)
+	}
+	if len(batch.Transactions) > 0 {
+		metrics.DBInsertDuration.WithLabelValues("transaction_models").Observe(time.Since(start).Seconds())
+		written["transaction_models"] = count
+	}
+
+	start = time.Now()
+	count, err = insertPriceTicks(ctx, tx, batch.PriceTicks)
+	if err != nil {
+		return nil, fmt.Errorf("error inserting price ticks for ledger %d: %w", batch.LedgerSequence, err)
+	}
+	if len(batch.PriceTicks) > 0 {
+		metrics.DBInsertDuration.WithLabelValues("price_ticks").Observe(time.Since(start).Seconds())
+		written["price_ticks"] = count
+	}
+
+	start = time.Now()
+	count, err = insertTrades(ctx, tx, batch.Trades)
+	if err != nil {
+		return nil, fmt.Errorf("error inserting trades for ledger %d: %w", batch.LedgerSequence, err)
+	}
+	if len(batch.Trades) > 0 {
+		metrics.DBInsertDuration.WithLabelValues("trades").Observe(time.Since(start).Seconds())
+		written["trades"] = count
+	}
+
+	start = time.Now()
+	count, err = insertPathPayments(ctx, tx, batch.PathPayments)
+	if err != nil {
+		return nil, fmt.Errorf("error inserting path payments for ledger %d: %w", batch.LedgerSequence, err)
+	}
+	if len(batch.PathPayments) > 0 {
+		metrics.DBInsertDuration.WithLabelValues("path_payments").Observe(time.Since(start).Seconds())
+		written["path_payments"] = count
+	}
+
+	start = time.Now()
+	count, err = insertPoolOperations(ctx, tx, batch.PoolOperations)
 	if err != nil {
-		fmt.Printf("Error checking if token exists: %v\n", err)
-		return false
+		return nil, fmt.Errorf("error inserting liquidity pool operations for ledger %d: %w", batch.LedgerSequence, err)
+	}
+	if len(batch.PoolOperations) > 0 {
+		metrics.DBInsertDuration.WithLabelValues("liquidity_pool_operations").Observe(time.Since(start).Seconds())
+		written["liquidity_pool_operations"] = count
+	}
+
+	start = time.Now()
+	count, err = insertFailedOperations(ctx, tx, batch.FailedOperations)
+	if err != nil {
+		return nil, fmt.Errorf("error inserting failed operations for ledger %d: %w", batch.LedgerSequence, err)
+	}
+	if len(batch.FailedOperations) > 0 {
+		metrics.DBInsertDuration.WithLabelValues("failed_operations").Observe(time.Since(start).Seconds())
+		written["failed_operations"] = count
 	}
-	return exists
+
+	start = time.Now()
+	count, err = upsertOffers(ctx, tx, batch.Offers)
+	if err != nil {
+		return nil, fmt.Errorf("error saving offers for ledger %d: %w", batch.LedgerSequence, err)
+	}
+	if len(batch.Offers) > 0 {
+		metrics.DBInsertDuration.WithLabelValues("offers").Observe(time.Since(start).Seconds())
+		written["offers"] = count
+	}
+
+	if len(batch.Tokens) > 0 {
+		start = time.Now()
+		if err := upsertTokens(ctx, tx, batch.Tokens); err != nil {
+			return nil, fmt.Errorf("error saving tokens for ledger %d: %w", batch.LedgerSequence, err)
+		}
+		metrics.DBInsertDuration.WithLabelValues("token_info").Observe(time.Since(start).Seconds())
+		written["token_info"] = int64(len(batch.Tokens))
+	}
+
+	if len(batch.Pools) > 0 {
+		start = time.Now()
+		if err := upsertPools(ctx, tx, batch.Pools); err != nil {
+			return nil, fmt.Errorf("error saving pools for ledger %d: %w", batch.LedgerSequence, err)
+		}
+		metrics.DBInsertDuration.WithLabelValues("liquidity_pools").Observe(time.Since(start).Seconds())
+		written["liquidity_pools"] = int64(len(batch.Pools))
+	}
+	return written, nil
 }
 
-func SaveTokenToDB(token models.TokenInfo) {
-	supplyBreakdownJSON, err := json.Marshal(token.SupplyBreakdown)
+// advanceCursor moves the named cursor forward to seq. The update only
+// matches while the stored value is behind seq, so replaying a ledger that
+// was already committed fails instead of inserting its rows twice.
+func advanceCursor(ctx context.Context, tx pgx.Tx, cursorName string, seq uint32) error {
+	tag, err := tx.Exec(
+		ctx,
+		`INSERT INTO ingest_state (cursor_name, ledger_sequence, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (cursor_name) DO UPDATE SET
+			ledger_sequence = EXCLUDED.ledger_sequence,
+			updated_at = EXCLUDED.updated_at
+		WHERE ingest_state.ledger_sequence < EXCLUDED.ledger_sequence`,
+		cursorName, seq,
+	)
 	if err != nil {
-		fmt.Printf("Error marshaling SupplyBreakdown to JSON: %v\n", err)
-		return
+		return fmt.Errorf("error advancing cursor %s: %w", cursorName, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: cursor %s is at or past ledger %d", ErrLedgerAlreadyCommitted, cursorName, seq)
 	}
+	return nil
+}
 
-	_, err = db.Exec(
-		context.Background(),
-		`INSERT INTO token_info (
-			contract_address, symbol, name, decimals, total_supply,
-			admin_address, is_auth_revocable, is_mintable, is_sac,
-			num_accounts, supply_breakdown
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-		ON CONFLICT (contract_address) DO UPDATE SET
-			symbol = EXCLUDED.symbol,
-			name = EXCLUDED.name,
-			decimals = EXCLUDED.decimals,
-			total_supply = EXCLUDED.total_supply,
-			admin_address = EXCLUDED.admin_address,
-			is_auth_revocable = EXCLUDED.is_auth_revocable,
-			is_mintable = EXCLUDED.is_mintable,
-			is_sac = EXCLUDED.is_sac,
-			num_accounts = EXCLUDED.num_accounts,
-			supply_breakdown = EXCLUDED.supply_breakdown`,
-		token.ContractAddress, token.Symbol, token.Name, token.Decimals, token.TotalSupply,
-		token.AdminAddress, token.IsAuthRevocable, token.IsMintable, token.IsSAC,
-		token.NumAccounts, supplyBreakdownJSON,
+// upsertLedger writes the ledger's row, replacing it when the ledger is
+// processed again so reprocessing refreshes its totals.
+func upsertLedger(ctx context.Context, tx pgx.Tx, l models.Ledger) error {
+	_, err := tx.Exec(
+		ctx,
+		`INSERT INTO ledgers (
+			ledger_sequence, ledger_hash, previous_ledger_hash, closed_at, protocol_version,
+			base_fee, base_reserve, total_coins, fee_pool,
+			successful_transaction_count, failed_transaction_count,
+			successful_operation_count, failed_operation_count, fee_charged,
+			soroban_non_refundable_fee_charged, soroban_refundable_fee_charged, soroban_rent_fee_charged
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+		ON CONFLICT (ledger_sequence) DO UPDATE SET
+			ledger_hash = EXCLUDED.ledger_hash,
+			previous_ledger_hash = EXCLUDED.previous_ledger_hash,
+			closed_at = EXCLUDED.closed_at,
+			protocol_version = EXCLUDED.protocol_version,
+			base_fee = EXCLUDED.base_fee,
+			base_reserve = EXCLUDED.base_reserve,
+			total_coins = EXCLUDED.total_coins,
+			fee_pool = EXCLUDED.fee_pool,
+			successful_transaction_count = EXCLUDED.successful_transaction_count,
+			failed_transaction_count = EXCLUDED.failed_transaction_count,
+			successful_operation_count = EXCLUDED.successful_operation_count,
+			failed_operation_count = EXCLUDED.failed_operation_count,
+			fee_charged = EXCLUDED.fee_charged,
+			soroban_non_refundable_fee_charged = EXCLUDED.soroban_non_refundable_fee_charged,
+			soroban_refundable_fee_charged = EXCLUDED.soroban_refundable_fee_charged,
+			soroban_rent_fee_charged = EXCLUDED.soroban_rent_fee_charged,
+			ingested_at = NOW()`,
+		l.Sequence, l.Hash, l.PreviousHash, l.ClosedAt, l.ProtocolVersion,
+		l.BaseFee, l.BaseReserve, l.TotalCoins, l.FeePool,
+		l.SuccessfulTransactionCount, l.FailedTransactionCount,
+		l.SuccessfulOperationCount, l.FailedOperationCount, l.FeeCharged,
+		l.SorobanNonRefundableFeeCharged, l.SorobanRefundableFeeCharged, l.SorobanRentFeeCharged,
 	)
+	return err
+}
+
+var transactionColumns = []string{
+	"block_time", "ledger_sequence", "transaction_hash", "operation_index", "event_index",
+	"dex_name", "source_account", "token_in", "token_out", "offer_id",
+	"dex_type", "pool_address", "matched_offer_id", "buyer_account",
+	"seller_account", "offer_buy_amount", "offer_sell_amount", "amount_bought",
+	"amount_sold", "offer_price", "dex_fee", "status", "order_matches", "passive",
+}
+
+func insertTransactions(ctx context.Context, tx pgx.Tx, transactions []models.TransactionModels) (int64, error) {
+	if len(transactions) == 0 {
+		return 0, nil
+	}
+
+	return copyIgnoringDuplicates(
+		ctx, tx, "transaction_models", transactionColumns,
+		"ledger_sequence, transaction_hash, operation_index, event_index",
+		pgx.CopyFromSlice(len(transactions), func(i int) ([]interface{}, error) {
+			transaction := transactions[i]
+			orderMatchesJSON, err := json.Marshal(transaction.OrderMatches)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal order matches to JSON: %w", err)
+			}
+
+			return []interface{}{
+				transaction.BlockTime, transaction.LedgerSequence, transaction.TransactionHash, transaction.OperationIndex, transaction.EventIndex,
+				transaction.DexName, transaction.SourceAccount, transaction.TokenIn, transaction.TokenOut, transaction.OfferID,
+				transaction.Dex_type, transaction.PoolAddress, transaction.MatchedOfferID, transaction.BuyerAccount,
+				transaction.SellerAccount, transaction.OfferBuyAmount, transaction.OfferSellAmount, transaction.AmountBought,
+				transaction.AmountSold, transaction.OfferPrice, transaction.DexFee, transaction.Status, orderMatchesJSON, transaction.Passive,
+			}, nil
+		}),
+	)
+}
+
+// copyIgnoringDuplicates bulk loads rows into a temporary staging table and
+// moves them into table with ON CONFLICT DO NOTHING on the natural key, so
+// replays, crashes mid-ledger and overlapping backfills never duplicate rows.
+// It returns the number of rows that were actually new.
+func copyIgnoringDuplicates(ctx context.Context, tx pgx.Tx, table string, columns []string, conflictKey string, rows pgx.CopyFromSource) (int64, error) {
+	return copyThroughStaging(ctx, tx, table, columns, rows, fmt.Sprintf("ON CONFLICT (%s) DO NOTHING", conflictKey))
+}
+
+// copyThroughStaging bulk loads rows into a temporary staging table shaped
+// like table, then moves them into table with one INSERT ... SELECT ending
+// in onConflict. It returns the number of rows inserted or updated.
+func copyThroughStaging(ctx context.Context, tx pgx.Tx, table string, columns []string, rows pgx.CopyFromSource, onConflict string) (int64, error) {
+	staging := "staging_" + table
+	columnList := strings.Join(columns, ", ")
+
+	_, err := tx.Exec(ctx, fmt.Sprintf(
+		"CREATE TEMP TABLE IF NOT EXISTS %s ON COMMIT DROP AS SELECT %s FROM %s WITH NO DATA",
+		staging, columnList, table,
+	))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create staging table for %s: %w", table, err)
+	}
+
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{staging}, columns, rows); err != nil {
+		return 0, fmt.Errorf("failed to copy into %s: %w", staging, err)
+	}
+
+	tag, err := tx.Exec(ctx, fmt.Sprintf(
+		"INSERT INTO %s (%s) SELECT %s FROM %s %s",
+		table, columnList, columnList, staging, onConflict,
+	))
 	if err != nil {
-		fmt.Printf("Error saving token to database: %v\n", err)
+		return 0, fmt.Errorf("failed to move rows into %s: %w", table, err)
 	}
+
+	if _, err := tx.Exec(ctx, "TRUNCATE "+staging); err != nil {
+		return 0, fmt.Errorf("failed to clear %s: %w", staging, err)
+	}
+	return tag.RowsAffected(), nil
 }
 
-func getLastSuccessFullLedgerInDb() (uint32, error) {
+// GetCursor returns the last ledger committed under the named cursor, or 0
+// when nothing has been committed under it yet.
+func GetCursor(ctx context.Context, cursorName string) (uint32, error) {
 	var lastLedger uint32
-	row := db.QueryRow(context.Background(), "SELECT MAX(ledger_sequence) FROM transaction_models")
+	row := db.QueryRow(ctx, "SELECT ledger_sequence FROM ingest_state WHERE cursor_name = $1", cursorName)
 	err := row.Scan(&lastLedger)
-	if err == pgx.ErrNoRows || lastLedger == 0 {
+	if err == pgx.ErrNoRows {
 		return 0, nil
 	} else if err != nil {
-		return 0, fmt.Errorf("error getting last successful ledger: %w", err)
+		return 0, fmt.Errorf("error reading cursor %s: %w", cursorName, err)
 	}
 	return lastLedger, nil
 }
 
-func PoolExistsInDb(poolAddress string) bool {
-	var exists bool
-	err := db.QueryRow(context.Background(), "SELECT EXISTS(SELECT 1 FROM liquidity_pools WHERE pool_address = $1)", poolAddress).Scan(&exists)
+// GetCursors returns the cursors whose name starts with prefix, keyed by the
+// rest of the name.
+func GetCursors(ctx context.Context, prefix string) (map[string]uint32, error) {
+	rows, err := db.Query(ctx, "SELECT cursor_name, ledger_sequence FROM ingest_state WHERE starts_with(cursor_name, $1)", prefix)
 	if err != nil {
-		fmt.Printf("Error checking if pool exists: %v\n", err)
-		return false
+		return nil, fmt.Errorf("error reading cursors %s*: %w", prefix, err)
+	}
+	defer rows.Close()
+
+	cursors := map[string]uint32{}
+	for rows.Next() {
+		var name string
+		var lastLedger uint32
+		if err := rows.Scan(&name, &lastLedger); err != nil {
+			return nil, fmt.Errorf("error reading cursors %s*: %w", prefix, err)
+		}
+		cursors[strings.TrimPrefix(name, prefix)] = lastLedger
 	}
-	return exists
+	return cursors, rows.Err()
 }
 
-func SavePoolToDB(pool models.LiquidityPool) {
-	_, err := db.Exec(
-		context.Background(),
-		`INSERT INTO liquidity_pools (
-			pool_address, token_a, token_b, fee_bps, type, created_at
-		) VALUES ($1, $2, $3, $4, $5, $6)
-		ON CONFLICT (pool_address) DO UPDATE SET
-			token_a = EXCLUDED.token_a,
-			token_b = EXCLUDED.token_b,
-			fee_bps = EXCLUDED.fee_bps,
-			type = EXCLUDED.type,
-			created_at = EXCLUDED.created_at`,
-		pool.PoolAddress, pool.TokenA, pool.TokenB, pool.FeeBps, pool.Type, pool.CreatedAt,
-	)
+// GetTokenAddresses returns the address of every token in token_info.
+func GetTokenAddresses(ctx context.Context) ([]string, error) {
+	rows, err := db.Query(ctx, "SELECT contract_address FROM token_info")
 	if err != nil {
-		fmt.Printf("Error saving pool to database: %v\n", err)
+		return nil, fmt.Errorf("error reading token addresses: %w", err)
+	}
+	tokens, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("error reading token addresses: %w", err)
+	}
+	return tokens, nil
+}
+
+// upsertTokens saves token details, replacing what was stored for a token
+// before, since details such as the supply change over time.
+func upsertTokens(ctx context.Context, tx pgx.Tx, tokens []models.TokenInfo) error {
+	for _, token := range tokens {
+		supplyBreakdownJSON, err := json.Marshal(token.SupplyBreakdown)
+		if err != nil {
+			return fmt.Errorf("failed to marshal supply breakdown of %s: %w", token.ContractAddress, err)
+		}
+
+		_, err = tx.Exec(
+			ctx,
+			`INSERT INTO token_info (
+				contract_address, symbol, name, decimals, total_supply,
+				admin_address, is_auth_revocable, is_mintable, is_sac,
+				num_accounts, supply_breakdown
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			ON CONFLICT (contract_address) DO UPDATE SET
+				symbol = EXCLUDED.symbol,
+				name = EXCLUDED.name,
+				decimals = EXCLUDED.decimals,
+				total_supply = EXCLUDED.total_supply,
+				admin_address = EXCLUDED.admin_address,
+				is_auth_revocable = EXCLUDED.is_auth_revocable,
+				is_mintable = EXCLUDED.is_mintable,
+				is_sac = EXCLUDED.is_sac,
+				num_accounts = EXCLUDED.num_accounts,
+				supply_breakdown = EXCLUDED.supply_breakdown`,
+			token.ContractAddress, token.Symbol, token.Name, token.Decimals, token.TotalSupply,
+			token.AdminAddress, token.IsAuthRevocable, token.IsMintable, token.IsSAC,
+			token.NumAccounts, supplyBreakdownJSON,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to save token %s: %w", token.ContractAddress, err)
+		}
 	}
+	return nil
+}
+
+// upsertPools saves pool details. created_at keeps the first time the pool
+// was seen.
+func upsertPools(ctx context.Context, tx pgx.Tx, pools []models.LiquidityPool) error {
+	for _, pool := range pools {
+		_, err := tx.Exec(
+			ctx,
+			`INSERT INTO liquidity_pools (
+				pool_address, token_a, token_b, fee_bps, type, created_at
+			) VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (pool_address) DO UPDATE SET
+				token_a = EXCLUDED.token_a,
+				token_b = EXCLUDED.token_b,
+				fee_bps = EXCLUDED.fee_bps,
+				type = EXCLUDED.type`,
+			pool.PoolAddress, pool.TokenA, pool.TokenB, pool.FeeBps, pool.Type, pool.CreatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to save pool %s: %w", pool.PoolAddress, err)
+		}
+	}
+	return nil
+}
+
+var offerColumns = []string{
+	"offer_id", "seller_account", "selling_asset", "buying_asset", "amount",
+	"price_n", "price_d", "price", "flags", "created_ledger",
+	"last_modified_ledger", "state", "last_transaction_hash", "updated_at",
 }
 
-func InsertPriceTicks(ticks []models.PriceTick) {
+// upsertOffers saves the latest state of each offer in one staged upsert. A
+// row never replaces a newer one, and created_ledger keeps the ledger the
+// offer was first seen created on.
+func upsertOffers(ctx context.Context, tx pgx.Tx, offers []models.Offer) (int64, error) {
+	offers = latestOffers(offers)
+	if len(offers) == 0 {
+		return 0, nil
+	}
+
+	return copyThroughStaging(
+		ctx, tx, "offers", offerColumns,
+		pgx.CopyFromSlice(len(offers), func(i int) ([]interface{}, error) {
+			offer := offers[i]
+			var createdLedger *uint32
+			if offer.CreatedLedger != 0 {
+				createdLedger = &offer.CreatedLedger
+			}
+			return []interface{}{
+				offer.OfferID, offer.SellerAccount, offer.SellingAsset, offer.BuyingAsset, offer.Amount,
+				offer.PriceN, offer.PriceD, offer.Price, offer.Flags, createdLedger,
+				offer.LastModifiedLedger, offer.State, offer.LastTransactionHash, offer.UpdatedAt,
+			}, nil
+		}),
+		`ON CONFLICT (offer_id) DO UPDATE SET
+			seller_account = EXCLUDED.seller_account,
+			selling_asset = EXCLUDED.selling_asset,
+			buying_asset = EXCLUDED.buying_asset,
+			amount = EXCLUDED.amount,
+			price_n = EXCLUDED.price_n,
+			price_d = EXCLUDED.price_d,
+			price = EXCLUDED.price,
+			flags = EXCLUDED.flags,
+			created_ledger = COALESCE(offers.created_ledger, EXCLUDED.created_ledger),
+			last_modified_ledger = EXCLUDED.last_modified_ledger,
+			state = EXCLUDED.state,
+			last_transaction_hash = EXCLUDED.last_transaction_hash,
+			updated_at = EXCLUDED.updated_at
+		WHERE offers.last_modified_ledger <= EXCLUDED.last_modified_ledger`,
+	)
+}
+
+// latestOffers keeps the last change of each offer in batch order, since one
+// INSERT cannot update the same row twice. An offer created and changed
+// again within the batch keeps the ledger it was created on.
+func latestOffers(offers []models.Offer) []models.Offer {
+	index := make(map[uint64]int, len(offers))
+	var latest []models.Offer
+	for _, offer := range offers {
+		i, ok := index[offer.OfferID]
+		if !ok {
+			index[offer.OfferID] = len(latest)
+			latest = append(latest, offer)
+			continue
+		}
+		if offer.CreatedLedger == 0 {
+			offer.CreatedLedger = latest[i].CreatedLedger
+		}
+		latest[i] = offer
+	}
+	return latest
+}
+
+var priceTickColumns = []string{
+	"ts", "asset_id", "source_id", "source_type",
+	"price_usd", "volume_usd", "base_volume", "quote_volume",
+	"ledger_seq", "tx_hash",
+}
+
+// insertPriceTicks saves ticks, dropping those of assets missing from the
+// assets table: price_ticks references it, and one such tick would
+// otherwise fail the commit of the whole ledger.
+func insertPriceTicks(ctx context.Context, tx pgx.Tx, ticks []models.PriceTick) (int64, error) {
 	if len(ticks) == 0 {
-		return
+		return 0, nil
 	}
 
-	tx, err := db.Begin(context.Background())
+	known, err := knownAssets(ctx, tx, ticks)
 	if err != nil {
-		fmt.Printf("Error starting transaction: %v\n", err)
-		return
+		return 0, err
 	}
-	defer func() {
-		if r := recover(); r != nil {
-			fmt.Printf("Recovered from panic in InsertPriceTicks, rolling back: %v\n", r)
-			tx.Rollback(context.Background())
+	kept, unknown := ticksOfKnownAssets(ticks, known)
+	if len(unknown) > 0 {
+		slog.Warn("Dropping price ticks of assets missing from the assets table", "assets", unknown, "dropped", len(ticks)-len(kept))
+	}
+	if len(kept) == 0 {
+		return 0, nil
+	}
+	ticks = kept
+
+	return copyIgnoringDuplicates(
+		ctx, tx, "price_ticks", priceTickColumns,
+		"asset_id, source_id, ts, tx_hash",
+		pgx.CopyFromSlice(len(ticks), func(i int) ([]interface{}, error) {
+			t := ticks[i]
+			return []interface{}{
+				t.Timestamp, t.AssetID, t.SourceID, t.SourceType,
+				t.PriceUSD, t.VolumeUSD, t.BaseVolume, t.QuoteVolume,
+				t.LedgerSeq, t.TxHash,
+			}, nil
+		}),
+	)
+}
+
+// knownAssets returns which of the assets of ticks are in the assets table.
+func knownAssets(ctx context.Context, tx pgx.Tx, ticks []models.PriceTick) (map[string]bool, error) {
+	ids := make([]string, 0, len(ticks))
+	for _, tick := range ticks {
+		ids = append(ids, tick.AssetID)
+	}
+	rows, err := tx.Query(ctx, "SELECT asset_id FROM assets WHERE asset_id = ANY($1)", ids)
+	if err != nil {
+		return nil, fmt.Errorf("error looking up price tick assets: %w", err)
+	}
+	known, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("error looking up price tick assets: %w", err)
+	}
+	set := make(map[string]bool, len(known))
+	for _, id := range known {
+		set[id] = true
+	}
+	return set, nil
+}
+
+// ticksOfKnownAssets keeps the ticks whose asset is in known and lists the
+// other assets once each, in order of appearance.
+func ticksOfKnownAssets(ticks []models.PriceTick, known map[string]bool) ([]models.PriceTick, []string) {
+	var kept []models.PriceTick
+	var unknown []string
+	for _, tick := range ticks {
+		if known[tick.AssetID] {
+			kept = append(kept, tick)
+		} else if !slices.Contains(unknown, tick.AssetID) {
+			unknown = append(unknown, tick.AssetID)
 		}
-	}()
+	}
+	return kept, unknown
+}
 
-	err = func() error {
-		_, err = tx.CopyFrom(
-			context.Background(),
-			pgx.Identifier{"price_ticks"},
-			[]string{
-				"ts", "asset_id", "source_id", "source_type",
-				"price_usd", "volume_usd", "base_volume", "quote_volume",
-				"ledger_seq", "tx_hash",
-			},
-			pgx.CopyFromSlice(len(ticks), func(i int) ([]interface{}, error) {
-				t := ticks[i]
-				return []interface{}{
-					t.Timestamp, t.AssetID, t.SourceID, t.SourceType,
-					t.PriceUSD, t.VolumeUSD, t.BaseVolume, t.QuoteVolume,
-					t.LedgerSeq, t.TxHash,
-				}, nil
-			}),
-		)
-		return err
-	}()
+var tradeColumns = []string{
+	"block_time", "ledger_sequence", "transaction_hash", "operation_index", "claim_index",
+	"operation_type", "taker_account", "maker_account", "maker_offer_id",
+	"base_asset", "quote_asset", "base_amount", "quote_amount", "price", "base_is_seller",
+	"liquidity_pool_id",
+}
+
+func insertTrades(ctx context.Context, tx pgx.Tx, trades []models.Trade) (int64, error) {
+	if len(trades) == 0 {
+		return 0, nil
+	}
+
+	return copyIgnoringDuplicates(
+		ctx, tx, "trades", tradeColumns,
+		"ledger_sequence, transaction_hash, operation_index, claim_index",
+		pgx.CopyFromSlice(len(trades), func(i int) ([]interface{}, error) {
+			t := trades[i]
+			return []interface{}{
+				t.BlockTime, t.LedgerSequence, t.TransactionHash, t.OperationIndex, t.ClaimIndex,
+				t.OperationType, t.TakerAccount, t.MakerAccount, t.MakerOfferID,
+				t.BaseAsset, t.QuoteAsset, t.BaseAmount, t.QuoteAmount, t.Price, t.BaseIsSeller,
+				nullIfEmpty(t.LiquidityPoolID),
+			}, nil
+		}),
+	)
+}
+
+var pathPaymentColumns = []string{
+	"block_time", "ledger_sequence", "transaction_hash", "operation_index", "operation_type",
+	"source_account", "destination_account", "source_asset", "destination_asset",
+	"path", "source_amount", "destination_amount",
+}
+
+func insertPathPayments(ctx context.Context, tx pgx.Tx, payments []models.PathPayment) (int64, error) {
+	if len(payments) == 0 {
+		return 0, nil
+	}
+
+	return copyIgnoringDuplicates(
+		ctx, tx, "path_payments", pathPaymentColumns,
+		"ledger_sequence, transaction_hash, operation_index",
+		pgx.CopyFromSlice(len(payments), func(i int) ([]interface{}, error) {
+			p := payments[i]
+			path := p.Path
+			if path == nil {
+				path = []string{}
+			}
+			return []interface{}{
+				p.BlockTime, p.LedgerSequence, p.TransactionHash, p.OperationIndex, p.OperationType,
+				p.SourceAccount, p.DestinationAccount, p.SourceAsset, p.DestinationAsset,
+				path, p.SourceAmount, p.DestinationAmount,
+			}, nil
+		}),
+	)
+}
+
+var poolOperationColumns = []string{
+	"block_time", "ledger_sequence", "transaction_hash", "operation_index",
+	"operation_type", "liquidity_pool_id", "account", "asset_a", "asset_b",
+	"amount_a", "amount_b", "shares", "reserve_a", "reserve_b", "total_shares",
+}
+
+func insertPoolOperations(ctx context.Context, tx pgx.Tx, ops []models.LiquidityPoolOperation) (int64, error) {
+	if len(ops) == 0 {
+		return 0, nil
+	}
+
+	return copyIgnoringDuplicates(
+		ctx, tx, "liquidity_pool_operations", poolOperationColumns,
+		"ledger_sequence, transaction_hash, operation_index",
+		pgx.CopyFromSlice(len(ops), func(i int) ([]interface{}, error) {
+			o := ops[i]
+			return []interface{}{
+				o.BlockTime, o.LedgerSequence, o.TransactionHash, o.OperationIndex, o.OperationType,
+				o.LiquidityPoolID, o.Account, o.AssetA, o.AssetB,
+				o.AmountA, o.AmountB, o.Shares, o.ReserveA, o.ReserveB, o.TotalShares,
+			}, nil
+		}),
+	)
+}
+
+var failedOperationColumns = []string{
+	"block_time", "ledger_sequence", "transaction_hash", "operation_index",
+	"operation_type", "source_account", "transaction_result_code", "result_code",
+	"fee_charged", "contract_id", "function_name",
+}
 
+func insertFailedOperations(ctx context.Context, tx pgx.Tx, ops []models.FailedOperation) (int64, error) {
+	if len(ops) == 0 {
+		return 0, nil
+	}
+
+	return copyIgnoringDuplicates(
+		ctx, tx, "failed_operations", failedOperationColumns,
+		"ledger_sequence, transaction_hash, operation_index",
+		pgx.CopyFromSlice(len(ops), func(i int) ([]interface{}, error) {
+			o := ops[i]
+			return []interface{}{
+				o.BlockTime, o.LedgerSequence, o.TransactionHash, o.OperationIndex,
+				o.OperationType, o.SourceAccount, o.TransactionResultCode, o.ResultCode,
+				o.FeeCharged, nullIfEmpty(o.ContractID), nullIfEmpty(o.FunctionName),
+			}, nil
+		}),
+	)
+}
+
+func nullIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// CountScopeRows counts the stored rows of scope within ledgers [from, to].
+func CountScopeRows(ctx context.Context, scope models.RowScope, from, to uint32) (int64, error) {
+	var count int64
+	where, args := scopeFilter(scope, from, to)
+	err := db.QueryRow(ctx, fmt.Sprintf(
+		"SELECT COUNT(*) FROM %s WHERE %s", pgx.Identifier{scope.Table}.Sanitize(), where,
+	), args...).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("error counting %s rows: %w", scope.Table, err)
+	}
+	return count, nil
+}
+
+// scopeFilter returns the WHERE clause and arguments selecting the rows of
+// scope within ledgers [from, to].
+func scopeFilter(scope models.RowScope, from, to uint32) (string, []any) {
+	ledgerColumn := pgx.Identifier{scope.LedgerColumn}.Sanitize()
+	if scope.Column == "" {
+		return ledgerColumn + " BETWEEN $1 AND $2", []any{from, to}
+	}
+	return fmt.Sprintf("%s = $1 AND %s BETWEEN $2 AND $3", pgx.Identifier{scope.Column}.Sanitize(), ledgerColumn),
+		[]any{scope.Value, from, to}
+}
+
+// ReplaceLedgerRange deletes the rows of the given scopes within ledgers
+// [from, to] and writes the freshly derived batches in their place, all in
+// one database transaction. Ingestion cursors are left untouched.
+func ReplaceLedgerRange(ctx context.Context, scopes []models.RowScope, from, to uint32, batches []models.LedgerBatch) error {
+	tx, err := db.Begin(ctx)
 	if err != nil {
-		fmt.Printf("Error inserting price ticks, rolling back: %v\n", err)
-		tx.Rollback(context.Background())
-		return
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, scope := range scopes {
+		where, args := scopeFilter(scope, from, to)
+		_, err := tx.Exec(ctx, fmt.Sprintf(
+			"DELETE FROM %s WHERE %s", pgx.Identifier{scope.Table}.Sanitize(), where,
+		), args...)
+		if err != nil {
+			return fmt.Errorf("error deleting %s rows: %w", scope.Table, err)
+		}
+	}
+
+	written := map[string]int64{}
+	for _, batch := range batches {
+		counts, err := insertBatch(ctx, tx, batch)
+		if err != nil {
+			return err
+		}
+		for table, count := range counts {
+			written[table] += count
+		}
 	}
 
-	if err = tx.Commit(context.Background()); err != nil {
-		fmt.Printf("Error committing price ticks: %v\n", err)
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error committing ledgers %d-%d: %w", from, to, err)
+	}
+	for table, count := range written {
+		metrics.RowsWritten.WithLabelValues(table).Add(float64(count))
 	}
+	return nil
 }
@@ -0,0 +1,135 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/celerfi/stellar-indexer-go/migrations"
+	"github.com/celerfi/stellar-indexer-go/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// useTestDB points the package at the TimescaleDB database named by
+// TEST_DATABASE_URL, migrated in a fresh schema dropped when the test ends.
+func useTestDB(t *testing.T) {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("set TEST_DATABASE_URL to a TimescaleDB database to run database tests")
+	}
+	ctx := context.Background()
+	schema := fmt.Sprintf("utils_test_%d", time.Now().UnixNano())
+
+	cfg, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.ConnConfig.RuntimeParams["search_path"] = schema + ",public"
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The extension must already be installed, or CREATE EXTENSION would put
+	// it in the test schema and the cleanup would drop it.
+	var installed bool
+	if err := pool.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'timescaledb')").Scan(&installed); err != nil || !installed {
+		pool.Close()
+		t.Skipf("timescaledb is not installed in TEST_DATABASE_URL (err %v)", err)
+	}
+	if _, err := pool.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+		pool.Close()
+		t.Fatal(err)
+	}
+	previous := db
+	db = pool
+	t.Cleanup(func() {
+		db = previous
+		pool.Exec(context.Background(), "DROP SCHEMA "+schema+" CASCADE")
+		pool.Close()
+	})
+	if _, err := migrations.Up(ctx, pool); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestCommitLedgerUnknownAsset checks that a price tick of an asset missing
+// from the assets table is dropped instead of failing the ledger commit.
+func TestCommitLedgerUnknownAsset(t *testing.T) {
+	useTestDB(t)
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "INSERT INTO assets (asset_id, asset_code, asset_type) VALUES ('XLM', 'XLM', 'classic')"); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	batch := models.LedgerBatch{
+		LedgerSequence: 10,
+		PriceTicks: []models.PriceTick{
+			{Timestamp: ts, AssetID: "XLM", SourceID: "reflector", SourceType: "oracle_onchain", PriceUSD: 0.1, LedgerSeq: 10, TxHash: "a"},
+			{Timestamp: ts, AssetID: "UNLISTED", SourceID: "reflector", SourceType: "oracle_onchain", PriceUSD: 2, LedgerSeq: 10, TxHash: "a"},
+		},
+	}
+	if err := CommitLedger(ctx, batch, "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	var assets []string
+	rows, err := db.Query(ctx, "SELECT asset_id FROM price_ticks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		var asset string
+		if err := rows.Scan(&asset); err != nil {
+			t.Fatal(err)
+		}
+		assets = append(assets, asset)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(assets, []string{"XLM"}) {
+		t.Errorf("price ticks of %v, want [XLM]", assets)
+	}
+	if cursor, err := GetCursor(ctx, "test"); err != nil || cursor != 10 {
+		t.Errorf("cursor = %d, %v, want 10", cursor, err)
+	}
+}
+
+func TestTicksOfKnownAssets(t *testing.T) {
+	ticks := []models.PriceTick{
+		{AssetID: "XLM", TxHash: "a"},
+		{AssetID: "BTC", TxHash: "a"},
+		{AssetID: "UNLISTED", TxHash: "a"},
+		{AssetID: "UNLISTED", TxHash: "b"},
+		{AssetID: "OTHER", TxHash: "b"},
+	}
+	kept, unknown := ticksOfKnownAssets(ticks, map[string]bool{"XLM": true, "BTC": true})
+	if want := ticks[:2]; !reflect.DeepEqual(kept, want) {
+		t.Errorf("kept %+v, want %+v", kept, want)
+	}
+	if want := []string{"UNLISTED", "OTHER"}; !reflect.DeepEqual(unknown, want) {
+		t.Errorf("unknown %v, want %v", unknown, want)
+	}
+}
+
+func TestLatestOffers(t *testing.T) {
+	offers := []models.Offer{
+		{OfferID: 7, CreatedLedger: 100, LastModifiedLedger: 100, State: OFFER_STATE_OPEN, Amount: 50},
+		{OfferID: 8, LastModifiedLedger: 100, State: OFFER_STATE_OPEN, Amount: 20},
+		{OfferID: 7, LastModifiedLedger: 100, State: OFFER_STATE_OPEN, Amount: 30},
+		{OfferID: 8, LastModifiedLedger: 100, State: OFFER_STATE_CONSUMED},
+		{OfferID: 7, LastModifiedLedger: 100, State: OFFER_STATE_CANCELLED},
+	}
+	want := []models.Offer{
+		{OfferID: 7, CreatedLedger: 100, LastModifiedLedger: 100, State: OFFER_STATE_CANCELLED},
+		{OfferID: 8, LastModifiedLedger: 100, State: OFFER_STATE_CONSUMED},
+	}
+	if got := latestOffers(offers); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
@@ -1,11 +1,9 @@
 package utils
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
+	"log/slog"
 	"math/big"
 	"net/http"
 	"strconv"
@@ -13,18 +11,55 @@ import (
 	"time"
 
 	"github.com/celerfi/stellar-indexer-go/config"
+	"github.com/celerfi/stellar-indexer-go/metrics"
 	"github.com/celerfi/stellar-indexer-go/models"
 	"github.com/stellar/go/clients/horizonclient"
+	rpcclient "github.com/stellar/go/clients/rpcclient"
+	hProtocol "github.com/stellar/go/protocols/horizon"
+	protocol "github.com/stellar/go/protocols/rpc"
 	"github.com/stellar/go/strkey"
 	"github.com/stellar/go/txnbuild"
 	"github.com/stellar/go/xdr"
 )
 
-// DefaultConfig returns sensible defaults for mainnet
-var rpc_config = models.GetTokenConfig{
-	RPCUrl:     config.RPC_URL,
-	HorizonUrl: "https://horizon.stellar.org",
-	Timeout:    10 * time.Second,
+// RPCClient is the part of a Soroban RPC client the token helpers use.
+type RPCClient interface {
+	SimulateTransaction(ctx context.Context, request protocol.SimulateTransactionRequest) (protocol.SimulateTransactionResponse, error)
+}
+
+// TokenClients are the RPC node and Horizon the token helpers query.
+type TokenClients struct {
+	RPC     RPCClient
+	Horizon horizonclient.ClientInterface
+	// Timeout bounds each RPC call.
+	Timeout time.Duration
+}
+
+// tokenClients points the token helpers at the RPC node and the Horizon of the active network
+var tokenClients TokenClients
+
+// NewTokenClients builds clients for the RPC node and Horizon of cfg.
+func NewTokenClients(cfg config.Config) TokenClients {
+	return TokenClients{
+		RPC: rpcclient.NewClient(cfg.RPC.URL, &http.Client{Timeout: cfg.RPC.Timeout}),
+		Horizon: &horizonclient.Client{
+			HorizonURL: cfg.Network.HorizonURL,
+			HTTP:       &http.Client{Timeout: cfg.Horizon.Timeout},
+		},
+		Timeout: cfg.RPC.Timeout,
+	}
+}
+
+// SetTokenConfig points the token helpers at the RPC node and Horizon of cfg.
+// Call it once at startup.
+func SetTokenConfig(cfg config.Config) {
+	SetTokenClients(NewTokenClients(cfg))
+}
+
+// SetTokenClients points the token helpers at the given clients, e.g. fakes
+// in tests.
+func SetTokenClients(clients TokenClients) {
+	tokenClients = clients
 }
 
 // GetTokenInfo is the main function to get all token information
@@ -41,29 +76,27 @@ func GetSorobanTokenInfo(contractAddress string) (*models.TokenInfo, error) {
 	}
 
 	// Get basic token info from contract
-	info.Symbol, err = getTokenSymbol(scAddr, rpc_config)
+	info.Symbol, err = getTokenSymbol(scAddr, tokenClients)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get symbol: %w", err)
 	}
-	fmt.Printf("Info Symbol: %s", info.Symbol)
 
-	info.Name, err = getTokenName(scAddr, rpc_config)
+	info.Name, err = getTokenName(scAddr, tokenClients)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get name: %w", err)
 	}
-	fmt.Printf("Info Name: %s", info.Name)
 
-	info.Decimals, err = getTokenDecimals(scAddr, rpc_config)
+	info.Decimals, err = getTokenDecimals(scAddr, tokenClients)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get decimals: %w", err)
 	}
-	fmt.Printf("Info Decimal: %s", info.Decimals)
+	slog.Debug("Fetched Soroban token info", "token", contractAddress, "symbol", info.Symbol, "name", info.Name, "decimals", info.Decimals)
 
 	// Try to get admin address (may fail for some contracts)
-	info.AdminAddress, _ = getTokenAdmin(scAddr, rpc_config)
+	info.AdminAddress, _ = getTokenAdmin(scAddr, tokenClients)
 
 	// Try to get total supply from contract (works for custom tokens)
-	info.TotalSupply, _ = getTokenTotalSupply(scAddr, rpc_config)
+	info.TotalSupply, _ = getTokenTotalSupply(scAddr, tokenClients)
 
 	// Check if it's a SAC by parsing the name format
 	assetCode, issuer, isSAC := parseSACName(info.Name)
@@ -71,13 +104,13 @@ func GetSorobanTokenInfo(contractAddress string) (*models.TokenInfo, error) {
 
 	// If it's a SAC and we don't have total supply, get it from Horizon
 	if isSAC && info.TotalSupply == "" {
-		supplyInfo, err := getClassicAssetSupply(assetCode, issuer, rpc_config)
+		supplyInfo, err := getClassicAssetSupply(assetCode, issuer, tokenClients)
 		if err == nil {
 			info.TotalSupply = fmt.Sprintf("%.7f", supplyInfo.Total)
 			info.SupplyBreakdown = supplyInfo
 
 			// Get number of holders
-			assetInfo, err := getClassicAssetInfo(assetCode, issuer, rpc_config)
+			assetInfo, err := getClassicAssetInfo(assetCode, issuer, tokenClients)
 			if err == nil {
 				info.NumAccounts = assetInfo.NumAccounts
 				info.IsAuthRevocable = assetInfo.Flags.AuthRevocable
@@ -88,7 +121,7 @@ func GetSorobanTokenInfo(contractAddress string) (*models.TokenInfo, error) {
 
 	// For non-SACs, get issuer flags if admin is a G-address
 	if !isSAC && strings.HasPrefix(info.AdminAddress, "G") {
-		mintable, revocable, err := getIssuerFlags(info.AdminAddress, rpc_config)
+		mintable, revocable, err := getIssuerFlags(info.AdminAddress, tokenClients)
 		if err == nil {
 			info.IsMintable = mintable
 			info.IsAuthRevocable = revocable
@@ -140,7 +173,10 @@ func createScAddressFromString(addressStr string) (xdr.ScAddress, error) {
 	return scAddr, nil
 }
 
-func callReadOnlyFunction(contractAddress xdr.ScAddress, functionName string, args xdr.ScVec, config models.GetTokenConfig) (xdr.ScVal, error) {
+func callReadOnlyFunction(contractAddress xdr.ScAddress, functionName string, args xdr.ScVec, clients TokenClients) (result xdr.ScVal, err error) {
+	start := time.Now()
+	defer func() { metrics.ObserveCall("rpc", functionName, start, err) }()
+
 	invokeContractArgs := xdr.InvokeContractArgs{
 		ContractAddress: contractAddress,
 		FunctionName:    xdr.ScSymbol(functionName),
@@ -181,78 +217,32 @@ func callReadOnlyFunction(contractAddress xdr.ScAddress, functionName string, ar
 		return xdr.ScVal{}, fmt.Errorf("failed to marshal tx envelope: %w", err)
 	}
 
-	rpcRequest := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"id":      1,
-		"method":  "simulateTransaction",
-		"params": map[string]interface{}{
-			"transaction": txEnvelopeXDR,
-		},
-	}
-
-	requestBody, err := json.Marshal(rpcRequest)
-	if err != nil {
-		return xdr.ScVal{}, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), config.Timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), clients.Timeout)
 	defer cancel()
 
-	req, err := http.NewRequestWithContext(ctx, "POST", config.RPCUrl, bytes.NewBuffer(requestBody))
-	if err != nil {
-		return xdr.ScVal{}, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{Timeout: config.Timeout}
-	resp, err := client.Do(req)
-	if err != nil {
-		return xdr.ScVal{}, fmt.Errorf("failed to make request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+	response, err := clients.RPC.SimulateTransaction(ctx, protocol.SimulateTransactionRequest{
+		Transaction: txEnvelopeXDR,
+	})
 	if err != nil {
-		return xdr.ScVal{}, fmt.Errorf("failed to read response: %w", err)
-	}
-
-	var rpcResponse struct {
-		Result struct {
-			Error   string `json:"error,omitempty"`
-			Results []struct {
-				XDR string `json:"xdr"`
-			} `json:"results"`
-		} `json:"result,omitempty"`
-		Error *struct {
-			Message string `json:"message"`
-		} `json:"error,omitempty"`
-	}
-
-	if err := json.Unmarshal(body, &rpcResponse); err != nil {
-		return xdr.ScVal{}, fmt.Errorf("failed to unmarshal response: %w", err)
+		return xdr.ScVal{}, fmt.Errorf("RPC error: %w", err)
 	}
-
-	if rpcResponse.Error != nil {
-		return xdr.ScVal{}, fmt.Errorf("RPC error: %s", rpcResponse.Error.Message)
-	}
-	if rpcResponse.Result.Error != "" {
-		return xdr.ScVal{}, fmt.Errorf("simulation error: %s", rpcResponse.Result.Error)
+	if response.Error != "" {
+		return xdr.ScVal{}, fmt.Errorf("simulation error: %s", response.Error)
 	}
-	if len(rpcResponse.Result.Results) == 0 {
+	if len(response.Results) == 0 || response.Results[0].ReturnValueXDR == nil {
 		return xdr.ScVal{}, fmt.Errorf("no results returned")
 	}
 
 	var scVal xdr.ScVal
-	if err := xdr.SafeUnmarshalBase64(rpcResponse.Result.Results[0].XDR, &scVal); err != nil {
+	if err := xdr.SafeUnmarshalBase64(*response.Results[0].ReturnValueXDR, &scVal); err != nil {
 		return xdr.ScVal{}, fmt.Errorf("failed to unmarshal result XDR: %w", err)
 	}
 
 	return scVal, nil
 }
 
-func getTokenSymbol(scAddr xdr.ScAddress, config models.GetTokenConfig) (string, error) {
-	scVal, err := callReadOnlyFunction(scAddr, "symbol", xdr.ScVec{}, config)
+func getTokenSymbol(scAddr xdr.ScAddress, clients TokenClients) (string, error) {
+	scVal, err := callReadOnlyFunction(scAddr, "symbol", xdr.ScVec{}, clients)
 	if err != nil {
 		return "", err
 	}
@@ -262,8 +252,8 @@ func getTokenSymbol(scAddr xdr.ScAddress, config models.GetTokenConfig) (string,
 	return string(scVal.MustStr()), nil
 }
 
-func getTokenName(scAddr xdr.ScAddress, config models.GetTokenConfig) (string, error) {
-	scVal, err := callReadOnlyFunction(scAddr, "name", xdr.ScVec{}, config)
+func getTokenName(scAddr xdr.ScAddress, clients TokenClients) (string, error) {
+	scVal, err := callReadOnlyFunction(scAddr, "name", xdr.ScVec{}, clients)
 	if err != nil {
 		return "", err
 	}
@@ -273,8 +263,8 @@ func getTokenName(scAddr xdr.ScAddress, config models.GetTokenConfig) (string, e
 	return string(scVal.MustStr()), nil
 }
 
-func getTokenDecimals(scAddr xdr.ScAddress, config models.GetTokenConfig) (uint32, error) {
-	scVal, err := callReadOnlyFunction(scAddr, "decimals", xdr.ScVec{}, config)
+func getTokenDecimals(scAddr xdr.ScAddress, clients TokenClients) (uint32, error) {
+	scVal, err := callReadOnlyFunction(scAddr, "decimals", xdr.ScVec{}, clients)
 	if err != nil {
 		return 0, err
 	}
@@ -284,8 +274,8 @@ func getTokenDecimals(scAddr xdr.ScAddress, config models.GetTokenConfig) (uint3
 	return uint32(*scVal.U32), nil
 }
 
-func getTokenTotalSupply(scAddr xdr.ScAddress, config models.GetTokenConfig) (string, error) {
-	scVal, err := callReadOnlyFunction(scAddr, "total_supply", xdr.ScVec{}, config)
+func getTokenTotalSupply(scAddr xdr.ScAddress, clients TokenClients) (string, error) {
+	scVal, err := callReadOnlyFunction(scAddr, "total_supply", xdr.ScVec{}, clients)
 	if err != nil {
 		return "", nil // Not an error, just means it's probably a SAC
 	}
@@ -295,8 +285,8 @@ func getTokenTotalSupply(scAddr xdr.ScAddress, config models.GetTokenConfig) (st
 	return int128PartsToBigInt(scVal.MustI128()).String(), nil
 }
 
-func getTokenAdmin(scAddr xdr.ScAddress, config models.GetTokenConfig) (string, error) {
-	scVal, err := callReadOnlyFunction(scAddr, "admin", xdr.ScVec{}, config)
+func getTokenAdmin(scAddr xdr.ScAddress, clients TokenClients) (string, error) {
+	scVal, err := callReadOnlyFunction(scAddr, "admin", xdr.ScVec{}, clients)
 	if err != nil {
 		return "", err
 	}
@@ -340,10 +330,8 @@ type classicAssetInfo struct {
 	}
 }
 
-func getClassicAssetInfo(assetCode, issuer string, config models.GetTokenConfig) (*classicAssetInfo, error) {
-	client := &horizonclient.Client{HorizonURL: config.HorizonUrl}
-
-	response, err := client.Assets(horizonclient.AssetRequest{
+func getClassicAssetInfo(assetCode, issuer string, clients TokenClients) (*classicAssetInfo, error) {
+	response, err := horizonAssets(clients.Horizon, horizonclient.AssetRequest{
 		ForAssetCode:   assetCode,
 		ForAssetIssuer: issuer,
 	})
@@ -374,10 +362,8 @@ func getClassicAssetInfo(assetCode, issuer string, config models.GetTokenConfig)
 	}, nil
 }
 
-func getClassicAssetSupply(assetCode, issuer string, config models.GetTokenConfig) (*models.SupplyBreakdown, error) {
-	client := &horizonclient.Client{HorizonURL: config.HorizonUrl}
-
-	response, err := client.Assets(horizonclient.AssetRequest{
+func getClassicAssetSupply(assetCode, issuer string, clients TokenClients) (*models.SupplyBreakdown, error) {
+	response, err := horizonAssets(clients.Horizon, horizonclient.AssetRequest{
 		ForAssetCode:   assetCode,
 		ForAssetIssuer: issuer,
 	})
@@ -405,10 +391,8 @@ func getClassicAssetSupply(assetCode, issuer string, config models.GetTokenConfi
 	}, nil
 }
 
-func getIssuerFlags(adminAddress string, config models.GetTokenConfig) (isMintable, isAuthRevocable bool, err error) {
-	client := &horizonclient.Client{HorizonURL: config.HorizonUrl}
-
-	account, err := client.AccountDetail(horizonclient.AccountRequest{
+func getIssuerFlags(adminAddress string, clients TokenClients) (isMintable, isAuthRevocable bool, err error) {
+	account, err := horizonAccountDetail(clients.Horizon, horizonclient.AccountRequest{
 		AccountID: adminAddress,
 	})
 	if err != nil {
@@ -422,6 +406,20 @@ func getIssuerFlags(adminAddress string, config models.GetTokenConfig) (isMintab
 	return isMintable, account.Flags.AuthRevocable, nil
 }
 
+func horizonAssets(client horizonclient.ClientInterface, request horizonclient.AssetRequest) (hProtocol.AssetsPage, error) {
+	start := time.Now()
+	page, err := client.Assets(request)
+	metrics.ObserveCall("horizon", "assets", start, err)
+	return page, err
+}
+
+func horizonAccountDetail(client horizonclient.ClientInterface, request horizonclient.AccountRequest) (hProtocol.Account, error) {
+	start := time.Now()
+	account, err := client.AccountDetail(request)
+	metrics.ObserveCall("horizon", "account_detail", start, err)
+	return account, err
+}
+
 func parseFloat(val string) float64 {
 	if val == "" {
 		return 0.0
@@ -434,9 +432,7 @@ func parseFloat(val string) float64 {
 }
 
 func GetClassicTokenInfo(issuerAddress string) (*models.TokenInfo, error) {
-	client := &horizonclient.Client{HorizonURL: rpc_config.HorizonUrl}
-
-	account, err := client.AccountDetail(horizonclient.AccountRequest{
+	account, err := horizonAccountDetail(tokenClients.Horizon, horizonclient.AccountRequest{
 		AccountID: issuerAddress,
 	})
 	if err != nil {
@@ -450,7 +446,7 @@ func GetClassicTokenInfo(issuerAddress string) (*models.TokenInfo, error) {
 
 	info.Name = account.HomeDomain
 
-	assets, err := client.Assets(horizonclient.AssetRequest{
+	assets, err := horizonAssets(tokenClients.Horizon, horizonclient.AssetRequest{
 		ForAssetIssuer: issuerAddress,
 	})
 	if err != nil || len(assets.Embedded.Records) == 0 {
@@ -462,14 +458,14 @@ func GetClassicTokenInfo(issuerAddress string) (*models.TokenInfo, error) {
 
 	info.ContractAddress = strings.Join([]string{record.Code, issuerAddress}, ":")
 
-	supply, err := getClassicAssetSupply(record.Code, issuerAddress, rpc_config)
+	supply, err := getClassicAssetSupply(record.Code, issuerAddress, tokenClients)
 	if err == nil {
 		info.TotalSupply = fmt.Sprintf("%.7f", supply.Total)
 		info.SupplyBreakdown = supply
 	}
 
 	// Holder count and flags
-	assetInfo, err := getClassicAssetInfo(record.Code, issuerAddress, rpc_config)
+	assetInfo, err := getClassicAssetInfo(record.Code, issuerAddress, tokenClients)
 	if err == nil {
 		info.NumAccounts = assetInfo.NumAccounts
 		info.IsAuthRevocable = assetInfo.Flags.AuthRevocable
@@ -485,7 +481,7 @@ func GetReflectorAssets(contractAddr string) ([]string, error) {
 		return nil, fmt.Errorf("invalid contract address: %w", err)
 	}
 
-	result, err := callReadOnlyFunction(scAddr, "assets", xdr.ScVec{}, rpc_config)
+	result, err := callReadOnlyFunction(scAddr, "assets", xdr.ScVec{}, tokenClients)
 	if err != nil {
 		return nil, fmt.Errorf("assets() call failed: %w", err)
 	}
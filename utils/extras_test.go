@@ -0,0 +1,294 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/celerfi/stellar-indexer-go/config"
+	"github.com/celerfi/stellar-indexer-go/stellartest"
+	"github.com/stellar/go/keypair"
+	hProtocol "github.com/stellar/go/protocols/horizon"
+	"github.com/stellar/go/protocols/horizon/base"
+	"github.com/stellar/go/strkey"
+	"github.com/stellar/go/xdr"
+)
+
+func testAccount(name string) string {
+	kp, err := keypair.FromRawSeed(sha256.Sum256([]byte(name)))
+	if err != nil {
+		panic(err)
+	}
+	return kp.Address()
+}
+
+func testContract(name string) string {
+	id := sha256.Sum256([]byte("contract " + name))
+	return strkey.MustEncode(strkey.VersionByteContract, id[:])
+}
+
+func stringVal(s string) xdr.ScVal {
+	str := xdr.ScString(s)
+	return xdr.ScVal{Type: xdr.ScValTypeScvString, Str: &str}
+}
+
+func symbolVal(s string) xdr.ScVal {
+	sym := xdr.ScSymbol(s)
+	return xdr.ScVal{Type: xdr.ScValTypeScvSymbol, Sym: &sym}
+}
+
+func u32Val(n uint32) xdr.ScVal {
+	v := xdr.Uint32(n)
+	return xdr.ScVal{Type: xdr.ScValTypeScvU32, U32: &v}
+}
+
+func i128Val(n int64) xdr.ScVal {
+	parts := xdr.Int128Parts{Lo: xdr.Uint64(n)}
+	return xdr.ScVal{Type: xdr.ScValTypeScvI128, I128: &parts}
+}
+
+func addressVal(t *testing.T, address string) xdr.ScVal {
+	t.Helper()
+	scAddr, err := createScAddressFromString(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return xdr.ScVal{Type: xdr.ScValTypeScvAddress, Address: &scAddr}
+}
+
+func vecVal(vals ...xdr.ScVal) xdr.ScVal {
+	vec := xdr.ScVec(vals)
+	p := &vec
+	return xdr.ScVal{Type: xdr.ScValTypeScvVec, Vec: &p}
+}
+
+// newFakeClients starts a fake RPC node and Horizon and returns clients
+// pointed at it.
+func newFakeClients(t *testing.T) (*stellartest.Server, TokenClients) {
+	t.Helper()
+	fake := stellartest.NewServer()
+	t.Cleanup(fake.Close)
+	cfg := config.Default()
+	cfg.RPC.URL = fake.RPCURL()
+	cfg.RPC.Timeout = 5 * time.Second
+	cfg.Network.HorizonURL = fake.HorizonURL()
+	return fake, NewTokenClients(cfg)
+}
+
+// withTokenClients points the package level helpers at clients for the
+// duration of the test.
+func withTokenClients(t *testing.T, clients TokenClients) {
+	t.Helper()
+	previous := tokenClients
+	SetTokenClients(clients)
+	t.Cleanup(func() { SetTokenClients(previous) })
+}
+
+func TestParseSACName(t *testing.T) {
+	issuer := testAccount("issuer")
+	tests := []struct {
+		name       string
+		wantCode   string
+		wantIssuer string
+		wantSAC    bool
+	}{
+		{"USDC:" + issuer, "USDC", issuer, true},
+		{"yXLM:" + issuer, "yXLM", issuer, true},
+		{"native", "", "", false},
+		{"Aquarius Token", "", "", false},
+		{"USDC:" + testContract("issuer"), "", "", false},
+		{"USDC:" + issuer + ":extra", "", "", false},
+		{"", "", "", false},
+	}
+	for _, tt := range tests {
+		code, gotIssuer, isSAC := parseSACName(tt.name)
+		if code != tt.wantCode || gotIssuer != tt.wantIssuer || isSAC != tt.wantSAC {
+			t.Errorf("parseSACName(%q) = (%q, %q, %v), want (%q, %q, %v)",
+				tt.name, code, gotIssuer, isSAC, tt.wantCode, tt.wantIssuer, tt.wantSAC)
+		}
+	}
+}
+
+func TestGetSorobanTokenInfoDetectsSAC(t *testing.T) {
+	fake, clients := newFakeClients(t)
+	withTokenClients(t, clients)
+
+	issuer := testAccount("usdc issuer")
+	token := testContract("usdc")
+	fake.SetContractResult(token, "symbol", stringVal("USDC"))
+	fake.SetContractResult(token, "name", stringVal("USDC:"+issuer))
+	fake.SetContractResult(token, "decimals", u32Val(7))
+	fake.SetContractResult(token, "admin", addressVal(t, issuer))
+	// A SAC has no total_supply; the supply comes from Horizon instead.
+	fake.SetContractError(token, "total_supply", "HostError: Error(Context, MissingValue)")
+
+	asset := hProtocol.AssetStat{
+		Accounts:                hProtocol.AssetStatAccounts{Authorized: 40, AuthorizedToMaintainLiabilities: 2, Unauthorized: 1},
+		Balances:                hProtocol.AssetStatBalances{Authorized: "1000.0000000"},
+		LiquidityPoolsAmount:    "250.5000000",
+		ContractsAmount:         "100.0000000",
+		ClaimableBalancesAmount: "0.2500000",
+		Flags:                   hProtocol.AccountFlags{AuthRevocable: true},
+	}
+	asset.Asset = base.Asset{Type: "credit_alphanum4", Code: "USDC", Issuer: issuer}
+	fake.AddAsset(asset)
+
+	info, err := GetSorobanTokenInfo(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.IsSAC || info.Symbol != "USDC" || info.Decimals != 7 || info.AdminAddress != issuer {
+		t.Errorf("got %+v, want a SAC USDC with 7 decimals administered by %s", info, issuer)
+	}
+	if info.TotalSupply != "1350.7500000" {
+		t.Errorf("TotalSupply = %q, want 1350.7500000", info.TotalSupply)
+	}
+	if info.SupplyBreakdown == nil || info.SupplyBreakdown.LiquidityPools != 250.5 {
+		t.Errorf("SupplyBreakdown = %+v, want 250.5 in liquidity pools", info.SupplyBreakdown)
+	}
+	if info.NumAccounts != 43 || !info.IsAuthRevocable || !info.IsMintable {
+		t.Errorf("got %d accounts, revocable %v, mintable %v; want 43, true, true",
+			info.NumAccounts, info.IsAuthRevocable, info.IsMintable)
+	}
+	if n := fake.Calls("/accounts"); n != 0 {
+		t.Errorf("a SAC should not look up issuer flags, got %d /accounts calls", n)
+	}
+}
+
+func TestGetSorobanTokenInfoCustomToken(t *testing.T) {
+	fake, clients := newFakeClients(t)
+	withTokenClients(t, clients)
+
+	admin := testAccount("aqua admin")
+	token := testContract("aqua")
+	fake.SetContractResult(token, "symbol", stringVal("AQUA"))
+	fake.SetContractResult(token, "name", stringVal("Aquarius Token"))
+	fake.SetContractResult(token, "decimals", u32Val(7))
+	fake.SetContractResult(token, "admin", addressVal(t, admin))
+	fake.SetContractResult(token, "total_supply", i128Val(5_000_000))
+
+	account := hProtocol.Account{AccountID: admin}
+	account.Thresholds = hProtocol.AccountThresholds{MedThreshold: 0, HighThreshold: 0}
+	account.Flags = hProtocol.AccountFlags{AuthRevocable: true}
+	fake.AddAccount(account)
+
+	info, err := GetSorobanTokenInfo(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.IsSAC {
+		t.Error("a custom token was detected as a SAC")
+	}
+	if info.TotalSupply != "5000000" {
+		t.Errorf("TotalSupply = %q, want 5000000", info.TotalSupply)
+	}
+	// The admin's master key is locked, so the token can no longer be minted.
+	if info.IsMintable || !info.IsAuthRevocable {
+		t.Errorf("mintable %v, revocable %v; want false, true", info.IsMintable, info.IsAuthRevocable)
+	}
+	if n := fake.Calls("/assets"); n != 0 {
+		t.Errorf("a custom token should not query /assets, got %d calls", n)
+	}
+}
+
+func TestGetSorobanTokenInfoSimulationError(t *testing.T) {
+	fake, clients := newFakeClients(t)
+	withTokenClients(t, clients)
+
+	token := testContract("not a token")
+	_, err := GetSorobanTokenInfo(token)
+	if err == nil || !strings.Contains(err.Error(), "failed to get symbol: simulation error") {
+		t.Fatalf("err = %v, want a symbol simulation error", err)
+	}
+	if n := fake.Calls("simulateTransaction:name"); n != 0 {
+		t.Errorf("lookups should stop at the first failure, got %d name calls", n)
+	}
+}
+
+func TestGetReflectorAssets(t *testing.T) {
+	fake, clients := newFakeClients(t)
+	withTokenClients(t, clients)
+
+	oracle := testContract("reflector")
+	usdc := testContract("usdc")
+	fake.SetContractResult(oracle, "assets", vecVal(
+		vecVal(symbolVal("Stellar"), addressVal(t, usdc)),
+		vecVal(symbolVal("Other"), symbolVal("BTC")),
+		vecVal(symbolVal("Other"), symbolVal("ETH")),
+	))
+
+	assets, err := GetReflectorAssets(oracle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{usdc, "BTC", "ETH"}
+	if strings.Join(assets, ",") != strings.Join(want, ",") {
+		t.Errorf("assets = %v, want %v", assets, want)
+	}
+}
+
+func TestGetReflectorAssetsErrors(t *testing.T) {
+	fake, clients := newFakeClients(t)
+	withTokenClients(t, clients)
+
+	notAVec := testContract("not a vec")
+	fake.SetContractResult(notAVec, "assets", symbolVal("BTC"))
+	badAsset := testContract("bad asset")
+	fake.SetContractResult(badAsset, "assets", vecVal(
+		vecVal(symbolVal("Other"), symbolVal("BTC")),
+		vecVal(symbolVal("Fiat"), symbolVal("EUR")),
+	))
+
+	tests := []struct {
+		contract string
+		wantErr  string
+	}{
+		{"not an address", "invalid contract address"},
+		{testContract("missing"), "assets() call failed"},
+		{notAVec, "unexpected result type from assets()"},
+		{badAsset, "decode asset[1]: unknown Asset variant: Fiat"},
+	}
+	for _, tt := range tests {
+		_, err := GetReflectorAssets(tt.contract)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("GetReflectorAssets(%s) err = %v, want %q", tt.contract, err, tt.wantErr)
+		}
+	}
+}
+
+func TestDecodeReflectorAsset(t *testing.T) {
+	usdc := testContract("usdc")
+	tests := []struct {
+		name    string
+		val     xdr.ScVal
+		want    string
+		wantErr string
+	}{
+		{"stellar", vecVal(symbolVal("Stellar"), addressVal(t, usdc)), usdc, ""},
+		{"other", vecVal(symbolVal("Other"), symbolVal("XLM")), "XLM", ""},
+		{"not a vec", symbolVal("XLM"), "", "expected ScVec with 2 elements"},
+		{"too short", vecVal(symbolVal("Other")), "", "expected ScVec with 2 elements"},
+		{"variant not a symbol", vecVal(stringVal("Other"), symbolVal("XLM")), "", "expected Symbol as first element"},
+		{"other with address", vecVal(symbolVal("Other"), addressVal(t, usdc)), "", "expected Symbol value in Other variant"},
+		{"stellar with symbol", vecVal(symbolVal("Stellar"), symbolVal("USDC")), "", "expected Address value in Stellar variant"},
+		{"unknown variant", vecVal(symbolVal("Fiat"), symbolVal("EUR")), "", "unknown Asset variant: Fiat"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeReflectorAsset(tt.val)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"math"
 	"math/big"
 
-	"github.com/celerfi/stellar-indexer-go/models"
 	"github.com/stellar/go/xdr"
 )
 
@@ -27,32 +26,6 @@ func FormatAsset(a xdr.Asset) string {
 	}
 }
 
-func PrettyPrintTransaction(t models.TransactionModels) {
-	fmt.Printf("BlockTime: %v\n", t.BlockTime)
-	fmt.Printf("LedgerSequence: %d\n", t.LedgerSequence)
-	fmt.Printf("TransactionHash: %s\n", t.TransactionHash)
-	fmt.Printf("OperationIndex: %d\n", t.OperationIndex)
-	fmt.Printf("DexName: %s\n", t.DexName)
-	fmt.Printf("SourceAccount: %s\n", t.SourceAccount)
-	fmt.Printf("TokenIn: %s\n", t.TokenIn)
-	fmt.Printf("TokenOut: %s\n", t.TokenOut)
-	fmt.Printf("OfferID: %d\n", t.OfferID)
-	fmt.Printf("MatchedOfferID: %d\n", t.MatchedOfferID)
-	fmt.Printf("BuyerAccount: %s\n", t.BuyerAccount)
-	fmt.Printf("SellerAccount: %s\n", t.SellerAccount)
-	fmt.Printf("OfferBuyAmount: %v\n", t.OfferBuyAmount)
-	fmt.Printf("OfferSellAmount: %v\n", t.OfferSellAmount)
-	fmt.Printf("AmountBought: %v\n", t.AmountBought)
-	fmt.Printf("AmountSold: %v\n", t.AmountSold)
-	fmt.Printf("OfferPrice: %v\n", t.OfferPrice)
-	fmt.Printf("Status: %s\n", t.Status)
-
-	fmt.Println("OrderMatches:")
-	for i, match := range t.OrderMatches {
-		fmt.Printf("  Match #%d: %+v\n", i+1, match)
-	}
-}
-
 func Int128ToDecimalFloat(parts xdr.Int128Parts, decimals int) float64 {
 	// 1. Convert Hi and Lo parts to a *big.Int
 	hi := big.NewInt(int64(parts.Hi))
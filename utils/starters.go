@@ -3,34 +3,26 @@ package utils
 import (
 	"context"
 	"errors"
-
-	"github.com/celerfi/stellar-indexer-go/config"
-	client "github.com/stellar/go/clients/rpcclient"
 )
 
-func GetStartLedger() (uint32, error) {
-	switch config.DEPLOYMENT_ENVIRONMENT {
+// GetStartLedger picks the ledger to resume from for the deployment
+// environment. lastCommitted reports the last ledger the sink committed, 0 if
+// none; latestLedger reports the newest ledger of the configured ledger source
+// and is used when there is nothing to resume from.
+func GetStartLedger(ctx context.Context, environment string, lastCommitted, latestLedger func(context.Context) (uint32, error)) (uint32, error) {
+	switch environment {
 	case "testing":
-		return getNodeLatestLedger()
+		return latestLedger(ctx)
 	case "production":
-		lastLedger, err := getLastSuccessFullLedgerInDb()
+		lastLedger, err := lastCommitted(ctx)
 		if err != nil {
 			return 0, err
 		}
 		if lastLedger == 0 {
-			return getNodeLatestLedger()
+			return latestLedger(ctx)
 		}
-		return lastLedger, nil
+		return lastLedger + 1, nil
 	default:
 		return 0, errors.New("set the deployment environment config: options (testing, production)")
 	}
 }
-
-func getNodeLatestLedger() (uint32, error) {
-	rpcClient := client.NewClient(config.RPC_URL, nil)
-	health, err := rpcClient.GetHealth(context.Background())
-	if err != nil {
-		return 0, err
-	}
-	return health.LatestLedger, nil
-}